@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Roles an account can hold. One account may hold several, e.g. a seller
+// who also bids. Auctioneers, who run live sessions, are appointed by an
+// admin.
+const (
+	roleBidder     = "bidder"
+	roleSeller     = "seller"
+	roleAdmin      = "admin"
+	roleAuctioneer = "auctioneer"
+)
+
+// loadAccount reads an account's profile and current roles
+func loadAccount(ctx context.Context, q queryer, id int) (models.AccountProfile, error) {
+	var p models.AccountProfile
+	err := q.QueryRowContext(ctx, `
+		SELECT a.id, a.name, COALESCE(a.email, ''), COALESCE(a.username, ''), a.email_verified_at IS NOT NULL,
+		       COALESCE(array_agg(r.role ORDER BY r.role) FILTER (WHERE r.role IS NOT NULL), '{}')
+		FROM accounts a
+		LEFT JOIN account_roles r ON r.account_id = a.id
+		WHERE a.id = $1
+		GROUP BY a.id
+	`, id).Scan(&p.ID, &p.Name, &p.Email, &p.Username, &p.EmailVerified, pq.Array(&p.Roles))
+	return p, err
+}
+
+// createAccount inserts an email account holding the given roles
+func createAccount(ctx context.Context, name, email, passwordHash string, roles ...string) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO accounts (name, email, password_hash) VALUES ($1, $2, $3) RETURNING id",
+		name, email, passwordHash).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	for _, role := range roles {
+		if err := grantRole(ctx, tx, id, role); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// auditRegistration records a new account and its initial roles
+func auditRegistration(c *gin.Context, id int, name, email string, roles ...string) {
+	audit(c, db, auditEvent{action: auditRegistered, actor: id, targetType: auditTargetAccount, targetID: id,
+		diff: auditDiff(nil, map[string]any{"name": name, "email": email, "roles": roles})})
+}
+
+// grantRole adds a role to an account; granting one it holds is a no-op
+func grantRole(ctx context.Context, ex execer, id int, role string) error {
+	_, err := ex.ExecContext(ctx,
+		"INSERT INTO account_roles (account_id, role) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, role)
+	return err
+}
+
+// hasRole reports whether the authenticated account holds the role
+func hasRole(c *gin.Context, role string) bool {
+	return slices.Contains(accountRoles(c), role)
+}
+
+// accountRoles returns the roles of the authenticated account
+func accountRoles(c *gin.Context) []string {
+	roles, _ := c.Get("roles")
+	held, _ := roles.([]string)
+	return held
+}
+
+// requireRole rejects authenticated callers that do not hold the role
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasRole(c, role) {
+			abortError(c, http.StatusForbidden, codeForbidden, "Requires "+role+" role")
+			return
+		}
+		c.Next()
+	}
+}
+
+// login signs in any account with its email, or an admin username, and
+// password. Sellers with two-factor authentication and every admin get an
+// MFA challenge instead of tokens.
+func login(c *gin.Context) {
+	var req struct {
+		Login    string `json:"login" binding:"required,notblank,max=255"`
+		Password string `json:"password" binding:"required,max=72"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	identifier := strings.TrimSpace(req.Login)
+
+	attempt, ok := beginLogin(c, identifier)
+	if !ok {
+		return
+	}
+	// An email match wins should an admin username look like someone's email
+	var id int
+	var hash string
+	err := db.QueryRowContext(ctx, `
+		SELECT id, password_hash FROM accounts
+		WHERE lower(email) = lower($1) OR lower(username) = lower($1)
+		ORDER BY (lower(email) = lower($1)) IS TRUE DESC
+		LIMIT 1
+	`, identifier).Scan(&id, &hash)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "account lookup failed", "error", err)
+	}
+	if !passwordMatches(hash, req.Password) {
+		attempt.fail(id)
+		return
+	}
+	attempt.succeed()
+	if requireSecondFactor(c, id) {
+		slog.InfoContext(ctx, "password accepted, second factor pending", "account_id", id)
+		return
+	}
+	completeLogin(c, id, nil)
+}
+
+// getAccount returns the signed-in account
+func getAccount(c *gin.Context) {
+	account, err := loadAccount(c.Request.Context(), db, accountID(c))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch account")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// addAccountRole lets a bidder start selling, or a seller start bidding,
+// without a second account. Only an admin can grant the admin role.
+func addAccountRole(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=bidder seller"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	id := accountID(c)
+	account, ok := changeRoles(c, id, auditRoleAdded, "Could not add role", func() error {
+		return grantRole(ctx, db, id, req.Role)
+	})
+	if !ok {
+		return
+	}
+	slog.InfoContext(ctx, "role added", "account_id", id, "role", req.Role)
+	c.JSON(http.StatusOK, account)
+}
+
+// adminAccountID parses the :id path parameter of the admin account routes
+func adminAccountID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid account ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// changeRoles applies a role change to an account and audits its roles
+// before and after. It writes the error response and reports false on
+// failure, including 404 for a missing account.
+func changeRoles(c *gin.Context, id int, action, failure string, change func() error) (models.AccountProfile, bool) {
+	ctx := c.Request.Context()
+	before, err := loadAccount(ctx, db, id)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Account not found")
+		return before, false
+	}
+	if err != nil {
+		respondDBError(c, err, failure)
+		return before, false
+	}
+	if err := change(); err != nil {
+		respondDBError(c, err, failure)
+		return before, false
+	}
+	after, err := loadAccount(ctx, db, id)
+	if err != nil {
+		respondDBError(c, err, failure)
+		return after, false
+	}
+	if diff := auditDiff(map[string]any{"roles": before.Roles}, map[string]any{"roles": after.Roles}); len(diff) > 0 {
+		audit(c, db, auditEvent{action: action, targetType: auditTargetAccount, targetID: id, diff: diff})
+	}
+	return after, true
+}
+
+// grantAccountRole lets an admin give an account a role
+func grantAccountRole(c *gin.Context) {
+	id, ok := adminAccountID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	role := c.Param("role")
+	account, ok := changeRoles(c, id, auditRoleGranted, "Could not grant role", func() error {
+		return grantRole(ctx, db, id, role)
+	})
+	if !ok {
+		return
+	}
+	slog.InfoContext(ctx, "role granted by admin", "admin_id", accountID(c), "account_id", id, "role", role)
+	c.JSON(http.StatusOK, account)
+}
+
+// revokeAccountRole lets an admin take a role away. Admins cannot drop
+// their own admin role, so the last admin cannot lock everyone out.
+func revokeAccountRole(c *gin.Context) {
+	id, ok := adminAccountID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	role := c.Param("role")
+	if role == roleAdmin && id == accountID(c) {
+		respondError(c, http.StatusConflict, codeConflict, "Admins cannot remove their own admin role")
+		return
+	}
+	account, ok := changeRoles(c, id, auditRoleRevoked, "Could not revoke role", func() error {
+		_, err := db.ExecContext(ctx, "DELETE FROM account_roles WHERE account_id = $1 AND role = $2", id, role)
+		return err
+	})
+	if !ok {
+		return
+	}
+	slog.InfoContext(ctx, "role revoked by admin", "admin_id", accountID(c), "account_id", id, "role", role)
+	c.JSON(http.StatusOK, account)
+}
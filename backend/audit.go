@@ -0,0 +1,239 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audited actions
+const (
+	auditLogin           = "login"
+	auditLoginFailed     = "login_failed"
+	auditRegistered      = "account_registered"
+	auditPasswordReset   = "password_reset"
+	auditMFAEnabled      = "mfa_enabled"
+	auditMFADisabled     = "mfa_disabled"
+	auditRecoveryCodes   = "mfa_recovery_codes_regenerated"
+	auditRoleAdded       = "role_added"
+	auditItemCreated     = "item_created"
+	auditItemUpdated     = "item_updated"
+	auditItemPublished   = "item_published"
+	auditItemCancelled   = "item_cancelled"
+	auditItemSplit       = "item_split"
+	auditItemMerged      = "item_merged"
+	auditSessionsRevoked = "admin_sessions_revoked"
+	auditAccountUnlocked = "admin_account_unlocked"
+	auditMFAReset        = "admin_mfa_reset"
+	auditRoleGranted     = "admin_role_granted"
+	auditRoleRevoked     = "admin_role_revoked"
+)
+
+// Kinds of record an audit event can concern
+const (
+	auditTargetAccount = "account"
+	auditTargetItem    = "item"
+)
+
+// Page sizes of the audit log API
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// auditEvent is an entry about to be written to the audit log
+type auditEvent struct {
+	action string
+	// actor overrides the signed-in account, for logins where the request
+	// is not yet authenticated
+	actor      int
+	targetType string
+	targetID   any
+	diff       map[string]models.AuditChange
+	details    map[string]string
+}
+
+// audit records who did what from where. The actor, IP, user agent and
+// request ID come from the request. ex lets the entry join the
+// transaction making the change; a failed write is logged, not returned,
+// so the audit log never blocks the action itself.
+func audit(c *gin.Context, ex execer, ev auditEvent) {
+	ctx := c.Request.Context()
+	if err := writeAuditEvent(ctx, ex, c, ev); err != nil {
+		slog.ErrorContext(ctx, "writing audit event failed", "action", ev.action, "error", err)
+	}
+}
+
+func writeAuditEvent(ctx context.Context, ex execer, c *gin.Context, ev auditEvent) error {
+	var actor sql.NullInt64
+	if id := cmp.Or(ev.actor, accountID(c)); id != 0 {
+		actor = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+	var target sql.NullString
+	if ev.targetID != nil {
+		target = sql.NullString{String: fmt.Sprint(ev.targetID), Valid: true}
+	}
+	diff, err := jsonOrNull(ev.diff)
+	if err != nil {
+		return err
+	}
+	details, err := jsonOrNull(ev.details)
+	if err != nil {
+		return err
+	}
+	_, err = ex.ExecContext(ctx, `
+		INSERT INTO audit_events (action, actor_id, target_type, target_id, ip, user_agent, request_id, diff, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, ev.action, actor, ev.targetType, target, c.ClientIP(), c.Request.UserAgent(), c.GetString(requestIDKey), diff, details)
+	return err
+}
+
+// jsonOrNull encodes a map for a JSONB column, storing NULL when it is empty
+func jsonOrNull[M ~map[string]V, V any](m M) (any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// auditDiff lists the fields whose values differ between before and after.
+// A nil before records a creation.
+func auditDiff(before, after map[string]any) map[string]models.AuditChange {
+	diff := map[string]models.AuditChange{}
+	for field, value := range after {
+		old, existed := before[field]
+		if before != nil && existed && reflect.DeepEqual(old, value) {
+			continue
+		}
+		diff[field] = models.AuditChange{Before: old, After: value}
+	}
+	for field, old := range before {
+		if _, ok := after[field]; !ok {
+			diff[field] = models.AuditChange{Before: old}
+		}
+	}
+	return diff
+}
+
+// listAuditEvents lets admins page through the audit log, newest first,
+// filtered by action, actor, target and time range
+func listAuditEvents(c *gin.Context) {
+	where := []string{"TRUE"}
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if v := c.Query("action"); v != "" {
+		add("action = $%d", v)
+	}
+	if v := c.Query("actor_id"); v != "" {
+		add("actor_id = $%d", v)
+	}
+	if v := c.Query("target_type"); v != "" {
+		add("target_type = $%d", v)
+	}
+	if v := c.Query("target_id"); v != "" {
+		add("target_id = $%d", v)
+	}
+	for _, bound := range []struct{ param, cond string }{
+		{"from", "occurred_at >= $%d"},
+		{"to", "occurred_at < $%d"},
+	} {
+		v := c.Query(bound.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid "+bound.param+" time",
+				models.FieldError{Field: bound.param, Message: "must be an RFC 3339 date-time"})
+			return
+		}
+		add(bound.cond, t)
+	}
+	if v := c.Query("cursor"); v != "" {
+		add("id < $%d", v)
+	}
+	limit := defaultAuditPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditPageSize {
+			respondError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid limit",
+				models.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxAuditPageSize)})
+			return
+		}
+		limit = n
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	args = append(args, limit+1)
+	rows, err := db.QueryContext(c.Request.Context(), `
+		SELECT id, occurred_at, action, actor_id, target_type, COALESCE(target_id, ''),
+		       COALESCE(ip, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''), diff, details
+		FROM audit_events
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		respondDBError(c, err, "Could not fetch audit events")
+		return
+	}
+	defer rows.Close()
+
+	page := models.AuditEventPage{Events: []models.AuditEvent{}}
+	for rows.Next() {
+		var ev models.AuditEvent
+		var actor sql.NullInt64
+		var diff, details []byte
+		err := rows.Scan(&ev.ID, &ev.OccurredAt, &ev.Action, &actor, &ev.TargetType, &ev.TargetID,
+			&ev.IP, &ev.UserAgent, &ev.RequestID, &diff, &details)
+		if err != nil {
+			respondDBError(c, err, "Could not fetch audit events")
+			return
+		}
+		if actor.Valid {
+			id := int(actor.Int64)
+			ev.ActorID = &id
+		}
+		if diff != nil {
+			if err := json.Unmarshal(diff, &ev.Diff); err != nil {
+				respondDBError(c, err, "Could not fetch audit events")
+				return
+			}
+		}
+		if details != nil {
+			if err := json.Unmarshal(details, &ev.Details); err != nil {
+				respondDBError(c, err, "Could not fetch audit events")
+				return
+			}
+		}
+		page.Events = append(page.Events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		respondDBError(c, err, "Could not fetch audit events")
+		return
+	}
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		page.NextCursor = page.Events[limit-1].ID
+	}
+	c.JSON(http.StatusOK, page)
+}
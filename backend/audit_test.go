@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"auction-system/models"
+)
+
+func TestAuditDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after map[string]any
+		want          map[string]models.AuditChange
+	}{
+		{
+			"creation records every field",
+			nil,
+			map[string]any{"name": "Clock", "price": 10.0},
+			map[string]models.AuditChange{"name": {After: "Clock"}, "price": {After: 10.0}},
+		},
+		{
+			"unchanged fields are left out",
+			map[string]any{"name": "Clock", "price": 10.0},
+			map[string]any{"name": "Clock", "price": 12.5},
+			map[string]models.AuditChange{"price": {Before: 10.0, After: 12.5}},
+		},
+		{
+			"nothing changed",
+			map[string]any{"roles": []string{"bidder"}},
+			map[string]any{"roles": []string{"bidder"}},
+			map[string]models.AuditChange{},
+		},
+		{
+			"slices compare by value",
+			map[string]any{"roles": []string{"bidder"}},
+			map[string]any{"roles": []string{"bidder", "seller"}},
+			map[string]models.AuditChange{"roles": {Before: []string{"bidder"}, After: []string{"bidder", "seller"}}},
+		},
+		{
+			"added field",
+			map[string]any{"name": "Clock"},
+			map[string]any{"name": "Clock", "reason": "damaged"},
+			map[string]models.AuditChange{"reason": {After: "damaged"}},
+		},
+		{
+			"removed field",
+			map[string]any{"name": "Clock", "reason": "damaged"},
+			map[string]any{"name": "Clock"},
+			map[string]models.AuditChange{"reason": {Before: "damaged"}},
+		},
+		{
+			"field set to nil",
+			map[string]any{"reason": "damaged"},
+			map[string]any{"reason": nil},
+			map[string]models.AuditChange{"reason": {Before: "damaged"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditDiff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditDiff = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"auction-system/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// bidRejection explains why a bid was refused, both to the client and to
+// the bid metrics
+type bidRejection struct {
+	status  int
+	code    string
+	reason  string
+	message string
+	details []models.FieldError
+}
+
+// bidTarget is what placeBid needs to know about the auction a bid is for
+type bidTarget struct {
+	format string
+	// live is set for lots of a live session, which are bid on while the
+	// auctioneer has them open
+	live bool
+}
+
+// validateBid checks a bid for units at amount each, by an account holding
+// roles, against the auction's current state and describes the auction. It
+// returns a rejection for bids the rules refuse and an error when the check
+// itself could not be completed.
+func validateBid(ctx context.Context, itemID string, bidderID int, roles []string, amount float64, units int) (target bidTarget, rej *bidRejection, err error) {
+	ctx, span := tracer.Start(ctx, "bid.validate", trace.WithAttributes(
+		attribute.String("auction.item_id", itemID),
+		attribute.Int("bid.bidder_id", bidderID),
+		attribute.Float64("bid.amount", amount),
+	))
+	defer func() {
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "bid validation failed")
+		case rej != nil:
+			span.SetAttributes(attribute.String("bid.rejected_reason", rej.reason))
+		}
+		span.End()
+	}()
+
+	var sellerID int
+	var status, format string
+	var startTime time.Time
+	var endTime sql.NullTime
+	err = db.QueryRowContext(ctx, `
+		SELECT seller_id, COALESCE(status, 'active'), format, start_time, end_time, session_id IS NOT NULL
+		FROM items WHERE id = $1 AND status IS DISTINCT FROM 'draft'
+	`, itemID).Scan(&sellerID, &status, &format, &startTime, &endTime, &target.live)
+	target.format = format
+	if err == sql.ErrNoRows {
+		return target, &bidRejection{http.StatusNotFound, codeNotFound, bidRejectedNotFound, "Item not found", nil}, nil
+	}
+	if err != nil {
+		return target, nil, err
+	}
+	if sellerID == bidderID {
+		return target, &bidRejection{http.StatusForbidden, codeForbidden, bidRejectedOwnItem, "Cannot bid on your own item", nil}, nil
+	}
+	if role := bidderRole(format); !slices.Contains(roles, role) {
+		return target, &bidRejection{http.StatusForbidden, codeForbidden, bidRejectedRole, "Requires " + role + " role", nil}, nil
+	}
+	if units > 1 && format != formatMultiUnit {
+		return target, &bidRejection{http.StatusBadRequest, codeValidationFailed, bidRejectedInvalid, "Invalid input",
+			[]models.FieldError{{Field: "quantity", Message: "only multi-unit auctions take bids for several units"}}}, nil
+	}
+	if status == "cancelled" {
+		return target, &bidRejection{http.StatusConflict, codeAuctionClosed, bidRejectedClosed, "Auction has been cancelled", nil}, nil
+	}
+	// Session lots run on the auctioneer's calls rather than the clock
+	if target.live && status == "upcoming" {
+		return target, &bidRejection{http.StatusConflict, codeAuctionNotStarted, bidRejectedNotStarted,
+			"Lot has not been opened yet; leave an absentee bid instead", nil}, nil
+	}
+	if !target.live && startTime.After(time.Now()) {
+		return target, &bidRejection{http.StatusConflict, codeAuctionNotStarted, bidRejectedNotStarted, "Auction has not started yet", nil}, nil
+	}
+	if status == "sold" || status == "unsold" || (endTime.Valid && endTime.Time.Before(time.Now())) {
+		return target, &bidRejection{http.StatusConflict, codeAuctionClosed, bidRejectedClosed, "Auction has ended", nil}, nil
+	}
+
+	switch {
+	case target.live:
+		// Checked against the bid increment under the lot's lock by
+		// placeLiveBid, since the auctioneer may hammer the lot meanwhile
+	case format == formatDutch:
+		// Accepting means offering at least the price on offer right now
+		var price float64
+		err = db.QueryRowContext(ctx, "SELECT "+dutchPriceSQL+" FROM items i WHERE i.id = $1", itemID).Scan(&price)
+		if err != nil {
+			return target, nil, err
+		}
+		if amount < price {
+			return target, &bidRejection{http.StatusUnprocessableEntity, codeBidTooLow, bidRejectedTooLow, "Bid must be at least the current price",
+				[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must be at least %.2f", price)}}}, nil
+		}
+	case format == formatSealed:
+		// Other bids are hidden, so the starting price is the only bar
+		var startingPrice float64
+		if err := db.QueryRowContext(ctx, "SELECT starting_price FROM items WHERE id = $1", itemID).Scan(&startingPrice); err != nil {
+			return target, nil, err
+		}
+		if amount < startingPrice {
+			return target, &bidRejection{http.StatusUnprocessableEntity, codeBidTooLow, bidRejectedTooLow, "Bid must be at least the starting price",
+				[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must be at least %.2f", startingPrice)}}}, nil
+		}
+	case format == formatMultiUnit:
+		if rej, err := outbidUnits(ctx, db, itemID, amount, units); rej != nil || err != nil {
+			return target, rej, err
+		}
+	case format == formatReverse:
+		if rej, err := undercut(ctx, db, itemID, amount); rej != nil || err != nil {
+			return target, rej, err
+		}
+	default:
+		if rej, err := outbid(ctx, itemID, amount); rej != nil || err != nil {
+			return target, rej, err
+		}
+	}
+
+	// A multi-unit bid commits the bidder to every unit it asks for
+	if amount*float64(units) > accountPolicy.UnverifiedBidLimit {
+		verified, err := emailVerified(ctx, bidderID)
+		if err != nil {
+			return target, nil, err
+		}
+		if !verified {
+			return target, &bidRejection{http.StatusForbidden, codeEmailUnverified, bidRejectedUnverified,
+				"Verify your email address to bid above the limit for unverified accounts",
+				[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must total at most %.2f until your email is verified", accountPolicy.UnverifiedBidLimit)}}}, nil
+		}
+	}
+	return target, nil, nil
+}
+
+// outbid requires a bid in an ascending auction to beat the highest bid so
+// far, or the starting price when there is none
+func outbid(ctx context.Context, itemID string, amount float64) (*bidRejection, error) {
+	var currentPrice float64
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(bid_amount), (SELECT starting_price FROM items WHERE id = $1)) FROM bids WHERE item_id = $1", itemID).Scan(&currentPrice)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= currentPrice {
+		return &bidRejection{http.StatusUnprocessableEntity, codeBidTooLow, bidRejectedTooLow, "Bid must be higher than current price",
+			[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must be greater than %.2f", currentPrice)}}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"auction-system/config"
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bidderContact is an account to notify about an auction it bid on
+type bidderContact struct {
+	name  string
+	email string
+}
+
+// cancelAuction lets the seller who listed an auction, or an admin, call it
+// off before it ends. Once bids exist, AUCTION_CANCEL_WITH_BIDS decides
+// whether that is refused or allowed with a reason; every bidder is emailed.
+func cancelAuction(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"max=1000"`
+	}
+	// The body is optional since a reason is only needed once bids exist
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	ctx := c.Request.Context()
+	itemID := c.Param("itemId")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not cancel auction")
+		return
+	}
+	defer tx.Rollback()
+
+	var name, status string
+	var sellerID int
+	var endTime sql.NullTime
+	var sessionID sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		"SELECT name, seller_id, COALESCE(status, 'active'), end_time, session_id FROM items WHERE id = $1 FOR UPDATE",
+		itemID).Scan(&name, &sellerID, &status, &endTime, &sessionID)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Auction not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not cancel auction")
+		return
+	}
+	if sellerID != accountID(c) && !hasRole(c, roleAdmin) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the seller or an admin can cancel this auction")
+		return
+	}
+	if status == "cancelled" {
+		respondError(c, http.StatusConflict, codeConflict, "Auction is already cancelled")
+		return
+	}
+	// Drafts have no end time and can be discarded this way too
+	if (status != "active" && status != "upcoming" && status != "draft") || (endTime.Valid && endTime.Time.Before(time.Now())) {
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Auction has ended")
+		return
+	}
+
+	bidders, err := auctionBidders(ctx, tx, itemID)
+	if err != nil {
+		respondDBError(c, err, "Could not cancel auction")
+		return
+	}
+	var bids int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM bids WHERE item_id = $1", itemID).Scan(&bids); err != nil {
+		respondDBError(c, err, "Could not cancel auction")
+		return
+	}
+	if bids > 0 {
+		if auctionRules.CancelWithBids == config.CancelWithBidsBlock {
+			respondError(c, http.StatusConflict, codeConflict, "Auctions that have received bids cannot be cancelled")
+			return
+		}
+		if reason == "" {
+			respondError(c, http.StatusBadRequest, codeValidationFailed, "A reason is required to cancel an auction with bids",
+				models.FieldError{Field: "reason", Message: "is required once bids have been placed"})
+			return
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE items
+		SET status = 'cancelled', cancelled_at = NOW(), cancelled_by = $2, cancellation_reason = NULLIF($3, '')
+		WHERE id = $1
+	`, itemID, accountID(c), reason)
+	if err != nil {
+		respondDBError(c, err, "Could not cancel auction")
+		return
+	}
+	// A live session ends with its last lot, cancelled or not
+	if sessionID.Valid {
+		if _, err := closeFinishedSession(ctx, tx, int(sessionID.Int64)); err != nil {
+			respondDBError(c, err, "Could not cancel auction")
+			return
+		}
+	}
+	after := map[string]any{"status": "cancelled"}
+	if reason != "" {
+		after["cancellation_reason"] = reason
+	}
+	audit(c, tx, auditEvent{action: auditItemCancelled, targetType: auditTargetItem, targetID: itemID,
+		diff:    auditDiff(map[string]any{"status": status}, after),
+		details: map[string]string{"bids": fmt.Sprint(bids)}})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not cancel auction")
+		return
+	}
+
+	for _, b := range bidders {
+		sendMailAsync(ctx, cancellationMail(b, name, reason))
+	}
+	slog.InfoContext(ctx, "auction cancelled", "item_id", itemID, "account_id", accountID(c), "bids", bids)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Auction cancelled successfully"})
+}
+
+// auctionBidders lists each account with an email that bid on the item,
+// including absentee bids left on a live-session lot
+func auctionBidders(ctx context.Context, tx *sql.Tx, itemID string) ([]bidderContact, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.name, a.email
+		FROM bids b
+		JOIN accounts a ON a.id = b.bidder_id
+		WHERE b.item_id = $1 AND a.email IS NOT NULL
+		UNION
+		SELECT a.name, a.email
+		FROM absentee_bids ab
+		JOIN accounts a ON a.id = ab.bidder_id
+		WHERE ab.item_id = $1 AND a.email IS NOT NULL
+	`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bidders []bidderContact
+	for rows.Next() {
+		var b bidderContact
+		if err := rows.Scan(&b.name, &b.email); err != nil {
+			return nil, err
+		}
+		bidders = append(bidders, b)
+	}
+	return bidders, rows.Err()
+}
+
+// cancellationMail tells a bidder that an auction they bid on was called off
+func cancellationMail(b bidderContact, item, reason string) mailMessage {
+	body := fmt.Sprintf("Hi %s,\n\nThe auction \"%s\" you bid on has been cancelled, so your bid no longer stands.\n", b.name, item)
+	if reason != "" {
+		body += fmt.Sprintf("\nReason given: %s\n", reason)
+	}
+	return mailMessage{
+		To: b.email,
+		// Item names are free text; keep them from breaking the header
+		Subject: "Auction cancelled: " + strings.Join(strings.Fields(item), " "),
+		Body:    body,
+	}
+}
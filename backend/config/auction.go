@@ -0,0 +1,80 @@
+package config
+
+import (
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Policies for cancelling an auction that has already received bids
+const (
+	// CancelWithBidsBlock refuses the cancellation
+	CancelWithBidsBlock = "block"
+	// CancelWithBidsReason allows it when the canceller gives a reason
+	CancelWithBidsReason = "reason"
+)
+
+// AuctionRules holds the platform limits applied to listings
+type AuctionRules struct {
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// MaxLeadTime is how far ahead an auction may be scheduled to start
+	MaxLeadTime time.Duration
+	// CancelWithBids is CancelWithBidsBlock or CancelWithBidsReason
+	CancelWithBids string
+}
+
+// NewAuctionRules reads listing limits from environment variables
+func NewAuctionRules() *AuctionRules {
+	return &AuctionRules{
+		MinDuration:    getDuration("AUCTION_MIN_DURATION", time.Hour),
+		MaxDuration:    getDuration("AUCTION_MAX_DURATION", 30*24*time.Hour),
+		MaxLeadTime:    getDuration("AUCTION_MAX_LEAD_TIME", 30*24*time.Hour),
+		CancelWithBids: getChoice("AUCTION_CANCEL_WITH_BIDS", CancelWithBidsReason, CancelWithBidsBlock, CancelWithBidsReason),
+	}
+}
+
+// getChoice reads one of the allowed values from the environment, falling
+// back to the default when unset or not allowed
+func getChoice(key, defaultValue string, allowed ...string) string {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	if !slices.Contains(allowed, value) {
+		slog.Warn("ignoring invalid setting", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getDuration parses a duration such as "90m" or "72h" from the environment,
+// falling back to the default when unset or invalid
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		slog.Warn("ignoring invalid duration", "key", key, "value", value, "default", defaultValue.String())
+		return defaultValue
+	}
+	return d
+}
+
+// getInt parses a positive integer from the environment, falling back to
+// the default when unset or invalid
+func getInt(key string, defaultValue int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		slog.Warn("ignoring invalid integer", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return n
+}
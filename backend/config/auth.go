@@ -0,0 +1,86 @@
+package config
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// AuthConfig holds token lifetimes and JWT signing settings
+type AuthConfig struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Algorithm is the only JWT "alg" accepted: HS256, RS256 or EdDSA
+	Algorithm string
+	Issuer    string
+	Audience  string
+	// SigningKeyID is the kid of the key new tokens are signed with
+	SigningKeyID string
+	// KeyFiles maps each kid to a file holding an HMAC secret or a PEM
+	// key. Every listed key verifies tokens, so a retired key can stay
+	// here (public half only, for RS256/EdDSA) until its tokens expire.
+	KeyFiles map[string]string
+	// Secret is a shorthand for a single HS256 key given inline
+	Secret string
+	// MFAIssuer is the name authenticator apps show for TOTP entries
+	MFAIssuer string
+}
+
+// NewAuthConfig reads token settings from environment variables
+func NewAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		AccessTokenTTL:  getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		Algorithm:       getEnv("JWT_ALGORITHM", "HS256"),
+		Issuer:          getEnv("JWT_ISSUER", "auction-system"),
+		Audience:        getEnv("JWT_AUDIENCE", "auction-api"),
+		SigningKeyID:    getEnv("JWT_SIGNING_KEY_ID", "default"),
+		KeyFiles:        parseKeyFiles(getEnv("JWT_KEY_FILES", "")),
+		Secret:          getEnv("JWT_SECRET", ""),
+		MFAIssuer:       getEnv("MFA_ISSUER", "Auction System"),
+	}
+}
+
+// parseKeyFiles reads "kid=path,kid=path" into a map
+func parseKeyFiles(value string) map[string]string {
+	files := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			slog.Warn("ignoring malformed JWT_KEY_FILES entry", "entry", entry)
+			continue
+		}
+		files[strings.TrimSpace(kid)] = strings.TrimSpace(path)
+	}
+	return files
+}
+
+// LoginThrottle holds the brute-force limits applied to login endpoints.
+// After each failure the next attempt must wait Backoff, doubling per
+// failure but never beyond Lockout; from MaxFailures (IPMaxFailures for a
+// client IP) on it is locked for Lockout, also doubling, up to MaxLockout. Failures older than Window are forgotten.
+type LoginThrottle struct {
+	MaxFailures   int
+	IPMaxFailures int
+	Backoff       time.Duration
+	Lockout       time.Duration
+	MaxLockout    time.Duration
+	Window        time.Duration
+}
+
+// NewLoginThrottle reads login limits from environment variables
+func NewLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{
+		MaxFailures:   getInt("LOGIN_MAX_FAILURES", 5),
+		IPMaxFailures: getInt("LOGIN_IP_MAX_FAILURES", 20),
+		Backoff:       getDuration("LOGIN_BACKOFF", time.Second),
+		Lockout:       getDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		MaxLockout:    getDuration("LOGIN_MAX_LOCKOUT", 24*time.Hour),
+		Window:        getDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+}
@@ -3,7 +3,7 @@ package config
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -63,7 +63,7 @@ func Connect() error {
 		return fmt.Errorf("unable to ping database: %v", err)
 	}
 
-	log.Println("Successfully connected to database")
+	slog.Info("connected to database pool", "database", cfg.DBName)
 	return nil
 }
 
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// MailConfig selects and configures the outgoing mail transport
+type MailConfig struct {
+	// Driver is "smtp", "file" (one .eml per message in Dir) or "log"
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	Dir          string
+	// AppURL is the frontend base URL used to build links in emails
+	AppURL string
+}
+
+// NewMailConfig reads mail settings from environment variables
+func NewMailConfig() *MailConfig {
+	return &MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", "log"),
+		From:         getEnv("MAIL_FROM", "Auction System <no-reply@localhost>"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		Dir:          getEnv("MAIL_DIR", "mail"),
+		AppURL:       getEnv("APP_URL", "http://localhost:3000"),
+	}
+}
+
+// AccountPolicy holds the token lifetimes for account recovery and the
+// limits placed on accounts that have not verified their email
+type AccountPolicy struct {
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	// UnverifiedBidLimit is the highest bid an unverified bidder may place
+	UnverifiedBidLimit float64
+}
+
+// NewAccountPolicy reads account settings from environment variables
+func NewAccountPolicy() *AccountPolicy {
+	return &AccountPolicy{
+		PasswordResetTTL:     getDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL: getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		UnverifiedBidLimit:   getFloat("UNVERIFIED_BID_LIMIT", 100),
+	}
+}
+
+// getFloat parses a non-negative number from the environment, falling back
+// to the default when unset or invalid
+func getFloat(key string, defaultValue float64) float64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		slog.Warn("ignoring invalid number", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return f
+}
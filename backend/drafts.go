@@ -0,0 +1,248 @@
+package main
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createDraft saves a listing the seller can keep editing before it goes
+// live. Drafts have no timing yet, are hidden from buyers and take no bids.
+// Reverse auctions are posted by buyers and go live at once, so they
+// cannot be drafted.
+func createDraft(c *gin.Context) {
+	var req struct {
+		Name          string           `json:"name" binding:"required,notblank,max=200"`
+		Description   string           `json:"description" binding:"max=5000"`
+		StartingPrice float64          `json:"starting_price" binding:"required,finite,gt=0,lte=99999999.99"`
+		Format        string           `json:"format" binding:"omitempty,oneof=english dutch sealed multi_unit"`
+		Dutch         *dutchTerms      `json:"dutch" binding:"required_if=Format dutch,excluded_unless=Format dutch"`
+		Sealed        *sealedTerms     `json:"sealed" binding:"required_if=Format sealed,excluded_unless=Format sealed"`
+		MultiUnit     *multiUnitTerms  `json:"multi_unit" binding:"required_if=Format multi_unit,excluded_unless=Format multi_unit"`
+		LotItems      []lotItemRequest `json:"lot_items" binding:"excluded_if=Format multi_unit,omitempty,min=2,max=100,dive"`
+		Relist        *relistTerms     `json:"relist"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if details := dutchProblems(req.StartingPrice, req.Dutch); len(details) > 0 {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input", details...)
+		return
+	}
+	ctx := c.Request.Context()
+	format := cmp.Or(req.Format, formatEnglish)
+	step, interval, floor := req.Dutch.columns()
+	quantity, clearing := req.MultiUnit.columns()
+	relistMax, relistDrop := req.Relist.columns()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not create draft")
+		return
+	}
+	defer tx.Rollback()
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO items (name, description, starting_price, seller_id, start_time, status,
+		                   format, price_step, price_interval_seconds, floor_price, sealed_pricing, quantity, clearing,
+		                   relist_max, relist_price_drop)
+		VALUES ($1, $2, $3, $4, NULL, 'draft', $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, req.Name, req.Description, req.StartingPrice, accountID(c), format, step, interval, floor, req.Sealed.column(),
+		quantity, clearing, relistMax, relistDrop).Scan(&id)
+	if err != nil {
+		respondDBError(c, err, "Could not create draft")
+		return
+	}
+	if err := insertLotItems(ctx, tx, id, req.LotItems); err != nil {
+		respondDBError(c, err, "Could not create draft")
+		return
+	}
+	created := map[string]any{
+		"name":           req.Name,
+		"description":    req.Description,
+		"starting_price": req.StartingPrice,
+		"format":         format,
+		"status":         "draft",
+	}
+	if req.Dutch != nil {
+		created["dutch"] = req.Dutch
+	}
+	if req.Sealed != nil {
+		created["sealed"] = req.Sealed
+	}
+	if req.MultiUnit != nil {
+		created["multi_unit"] = req.MultiUnit
+	}
+	if len(req.LotItems) > 0 {
+		created["lot_size"] = len(req.LotItems)
+	}
+	if req.Relist != nil {
+		created["relist"] = req.Relist
+	}
+	audit(c, tx, auditEvent{action: auditItemCreated, targetType: auditTargetItem, targetID: id,
+		diff: auditDiff(nil, created)})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not create draft")
+		return
+	}
+
+	item, err := loadItem(ctx, fmt.Sprint(id))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// publishItem checks a draft against the same rules as a new listing and
+// puts it live, now or from start_time, until end_time
+func publishItem(c *gin.Context) {
+	var req struct {
+		StartTime *time.Time `json:"start_time" binding:"omitempty,auction_start"`
+		EndTime   time.Time  `json:"end_time" binding:"required,auction_end"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	itemID := c.Param("itemId")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not publish auction")
+		return
+	}
+	defer tx.Rollback()
+
+	var name, description, status string
+	var startingPrice float64
+	var sellerID int
+	var terms listingTerms
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, COALESCE(description, ''), starting_price, seller_id, COALESCE(status, 'active'), `+listingTermsSQL+`
+		FROM items WHERE id = $1 FOR UPDATE
+	`, itemID).Scan(append([]any{&name, &description, &startingPrice, &sellerID, &status}, terms.dest()...)...)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Auction not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not publish auction")
+		return
+	}
+	if sellerID != accountID(c) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the seller can publish this auction")
+		return
+	}
+	if status != "draft" {
+		respondError(c, http.StatusConflict, codeConflict, "Only drafts can be published")
+		return
+	}
+	details := append(listingProblems(name, description, startingPrice), terms.problems(startingPrice)...)
+	if len(details) > 0 {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Draft is not ready to publish", details...)
+		return
+	}
+
+	status = "active"
+	after := map[string]any{"status": status, "end_time": req.EndTime}
+	if req.StartTime != nil {
+		status = "upcoming"
+		after["status"] = status
+		after["start_time"] = *req.StartTime
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE items SET status = $2, start_time = COALESCE($3, CURRENT_TIMESTAMP), end_time = $4
+		WHERE id = $1
+	`, itemID, status, req.StartTime, req.EndTime)
+	if err != nil {
+		respondDBError(c, err, "Could not publish auction")
+		return
+	}
+	audit(c, tx, auditEvent{action: auditItemPublished, targetType: auditTargetItem, targetID: itemID,
+		diff: auditDiff(map[string]any{"status": "draft"}, after)})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not publish auction")
+		return
+	}
+
+	item, err := loadItem(ctx, itemID)
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// listingProblems checks a stored listing against the rules createItem
+// applies to request bodies, since a draft may predate a rule change
+func listingProblems(name, description string, startingPrice float64) []models.FieldError {
+	var details []models.FieldError
+	if strings.TrimSpace(name) == "" {
+		details = append(details, models.FieldError{Field: "name", Message: "is required"})
+	} else if len([]rune(name)) > 200 {
+		details = append(details, models.FieldError{Field: "name", Message: "must be at most 200 characters"})
+	}
+	if len([]rune(description)) > 5000 {
+		details = append(details, models.FieldError{Field: "description", Message: "must be at most 5000 characters"})
+	}
+	if startingPrice <= 0 || startingPrice > maxPrice {
+		details = append(details, models.FieldError{Field: "starting_price", Message: fmt.Sprintf("must be between 0.01 and %.2f", maxPrice)})
+	}
+	return details
+}
+
+// listingTermsSQL selects the format terms of an item read by
+// listingTerms.dest
+const listingTermsSQL = `format, price_step, price_interval_seconds, floor_price, sealed_pricing, bid_decrement, quantity, clearing`
+
+// listingTerms are the format terms of a stored listing
+type listingTerms struct {
+	format                 string
+	step, floor, decrement sql.NullFloat64
+	interval               sql.NullInt64
+	pricing, clearing      sql.NullString
+	quantity               int
+}
+
+// dest returns scan destinations for the columns of listingTermsSQL
+func (t *listingTerms) dest() []any {
+	return []any{&t.format, &t.step, &t.interval, &t.floor, &t.pricing, &t.decrement, &t.quantity, &t.clearing}
+}
+
+// storedTerms holds stored terms in the shape of a listing request, so
+// their problems are reported under the same field paths
+type storedTerms struct {
+	Dutch     *dutchTerms     `json:"dutch"`
+	Sealed    *sealedTerms    `json:"sealed"`
+	MultiUnit *multiUnitTerms `json:"multi_unit"`
+}
+
+// problems checks stored terms against the rules for new listings of the
+// format, with the given starting price, so an edit that breaks them is
+// reported by field rather than by the database. Reverse auctions are
+// never drafts; their terms are checked when the buyer edits the ceiling
+// of one that has not started.
+func (t listingTerms) problems(startingPrice float64) []models.FieldError {
+	switch t.format {
+	case formatDutch:
+		dutch := &dutchTerms{PriceStep: t.step.Float64, IntervalSeconds: int(t.interval.Int64), FloorPrice: t.floor.Float64}
+		return append(structProblems(&storedTerms{Dutch: dutch}), dutchProblems(startingPrice, dutch)...)
+	case formatSealed:
+		return structProblems(&storedTerms{Sealed: &sealedTerms{Pricing: t.pricing.String}})
+	case formatMultiUnit:
+		return structProblems(&storedTerms{MultiUnit: &multiUnitTerms{Quantity: t.quantity, Clearing: t.clearing.String}})
+	case formatReverse:
+		if t.decrement.Float64 >= startingPrice {
+			return []models.FieldError{{Field: "bid_decrement", Message: fmt.Sprintf("must be less than the ceiling price %.2f", startingPrice)}}
+		}
+	}
+	return nil
+}
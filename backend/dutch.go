@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auction formats
+const (
+	formatEnglish = "english"
+	formatDutch   = "dutch"
+	formatSealed  = "sealed"
+)
+
+// dutchStepsSQL counts the price drops of a Dutch auction aliased as i. It
+// reads the database clock so every server instance agrees on the price.
+const dutchStepsSQL = `GREATEST(0, FLOOR(EXTRACT(EPOCH FROM (NOW() - i.start_time)) / i.price_interval_seconds))`
+
+// dutchPriceSQL is the price a Dutch auction i is offering right now
+const dutchPriceSQL = `GREATEST(i.floor_price, i.starting_price - ` + dutchStepsSQL + ` * i.price_step)`
+
+// dutchNextDropSQL is when the price of a Dutch auction i falls next, or
+// NULL once it is at the floor or has been accepted. Needs bids b joined.
+const dutchNextDropSQL = `CASE
+		WHEN i.format = 'dutch' AND i.start_time IS NOT NULL AND COUNT(b.id) = 0 AND ` + dutchPriceSQL + ` > i.floor_price
+		THEN i.start_time + (` + dutchStepsSQL + ` + 1) * i.price_interval_seconds * INTERVAL '1 second'
+	END`
+
+// dutchTerms configures the falling price of a new Dutch auction
+type dutchTerms struct {
+	PriceStep       float64 `json:"price_step" binding:"required,finite,gt=0,lte=99999999.99"`
+	IntervalSeconds int     `json:"interval_seconds" binding:"required,min=1,max=86400"`
+	FloorPrice      float64 `json:"floor_price" binding:"required,finite,gt=0,lte=99999999.99"`
+}
+
+// columns returns the values stored in price_step, price_interval_seconds
+// and floor_price, which are NULL for other formats
+func (t *dutchTerms) columns() (step, interval, floor any) {
+	if t == nil {
+		return nil, nil, nil
+	}
+	return t.PriceStep, t.IntervalSeconds, t.FloorPrice
+}
+
+// dutchProblems checks the terms of a Dutch auction against its starting
+// price, since the floor cannot be above where the price starts
+func dutchProblems(startingPrice float64, t *dutchTerms) []models.FieldError {
+	if t != nil && t.FloorPrice > startingPrice {
+		return []models.FieldError{{Field: "dutch.floor_price", Message: fmt.Sprintf("must be at most the starting price %.2f", startingPrice)}}
+	}
+	return nil
+}
+
+// acceptDutchPrice sells a Dutch auction to the first bidder to accept its
+// price. The bid is recorded at the price on offer, which may have dropped
+// below the amount sent since it was checked.
+func acceptDutchPrice(c *gin.Context, itemID string, bidderID int) {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	defer tx.Rollback()
+
+	// A concurrent acceptance waits on the row lock and then no longer
+	// matches the status. Auctions past their start count as open even
+	// before the lifecycle worker has marked them active.
+	var price float64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE items i SET status = 'sold', closed_at = NOW()
+		WHERE i.id = $1 AND i.format = 'dutch' AND i.status IN ('active', 'upcoming')
+		  AND i.start_time <= NOW() AND i.end_time > NOW()
+		RETURNING `+dutchPriceSQL, itemID).Scan(&price)
+	if err == sql.ErrNoRows {
+		bidRejected(bidRejectedClosed)
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Another bidder accepted the price first")
+		return
+	}
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO bids (item_id, bidder_id, bid_amount) VALUES ($1, $2, $3)", itemID, bidderID, price)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	bidAccepted()
+	auctionsClosedTotal.WithLabelValues("sold").Inc()
+	slog.InfoContext(ctx, "dutch auction sold", "item_id", itemID, "bidder_id", bidderID, "price", price)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: fmt.Sprintf("You won the auction at %.2f", price)})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Error codes returned in the "code" field of the error envelope. Clients
+// may rely on these; the messages are for humans and can change.
+const (
+	codeInvalidRequest    = "invalid_request"
+	codeValidationFailed  = "validation_failed"
+	codeUnauthorized      = "unauthorized"
+	codeInvalidToken      = "invalid_token"
+	codeTokenExpired      = "token_expired"
+	codeTokenReused       = "token_reused"
+	codeSessionRevoked    = "session_revoked"
+	codeInvalidLogin      = "invalid_credentials"
+	codeInvalidMFACode    = "invalid_mfa_code"
+	codeForbidden         = "forbidden"
+	codeEmailUnverified   = "email_unverified"
+	codeTooManyAttempts   = "too_many_attempts"
+	codeAccountLocked     = "account_locked"
+	codeNotFound          = "not_found"
+	codeConflict          = "conflict"
+	codeAuctionClosed     = "auction_closed"
+	codeAuctionNotStarted = "auction_not_started"
+	codeBidTooLow         = "bid_too_low"
+	codeBidTooHigh        = "bid_too_high"
+	codeInternal          = "internal_error"
+)
+
+// PostgreSQL error codes we translate into client errors
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgInvalidText         = "22P02"
+	pgNumericOutOfRange   = "22003"
+	pgStringTooLong       = "22001"
+)
+
+// newError builds the error envelope for the current request
+func newError(c *gin.Context, code, message string, details ...models.FieldError) models.ErrorResponse {
+	return models.ErrorResponse{Error: models.ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: c.GetString(requestIDKey),
+	}}
+}
+
+// respondError writes an error envelope with the given status
+func respondError(c *gin.Context, status int, code, message string, details ...models.FieldError) {
+	c.JSON(status, newError(c, code, message, details...))
+}
+
+// abortError writes an error envelope and stops the handler chain
+func abortError(c *gin.Context, status int, code, message string, details ...models.FieldError) {
+	c.AbortWithStatusJSON(status, newError(c, code, message, details...))
+}
+
+// respondDBError maps a database error onto the matching HTTP status.
+// Constraint violations become client errors; anything unexpected is
+// logged and reported as a 500 with the fallback message.
+func respondDBError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, codeNotFound, "Not found")
+		return
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pgUniqueViolation:
+			respondError(c, http.StatusConflict, codeConflict, "Resource already exists", constraintField(pqErr))
+			return
+		case pgForeignKeyViolation:
+			respondError(c, http.StatusConflict, codeConflict, "Referenced resource does not exist", constraintField(pqErr))
+			return
+		case pgNotNullViolation, pgCheckViolation, pgInvalidText, pgNumericOutOfRange, pgStringTooLong:
+			respondError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input", constraintField(pqErr))
+			return
+		}
+	}
+
+	slog.ErrorContext(c.Request.Context(), "database error", "error", err)
+	respondError(c, http.StatusInternalServerError, codeInternal, fallback)
+}
+
+// constraintField reports which column a constraint violation concerns
+func constraintField(err *pq.Error) models.FieldError {
+	field := err.Column
+	if field == "" {
+		field = err.Constraint
+	}
+	return models.FieldError{Field: field, Message: err.Message}
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation
+}
+
+// recoverPanic turns a handler panic into a 500 error envelope
+func recoverPanic(c *gin.Context, recovered any) {
+	slog.ErrorContext(c.Request.Context(), "panic serving request", "panic", recovered)
+	abortError(c, http.StatusInternalServerError, codeInternal, "Internal server error")
+}
+
+// notFound handles requests that match no route
+func notFound(c *gin.Context) {
+	respondError(c, http.StatusNotFound, codeNotFound, "Route not found")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shuttingDown flips to true once a termination signal arrives so load
+// balancers stop routing new traffic while in-flight requests drain
+var shuttingDown atomic.Bool
+
+// healthz reports that the process is alive
+func healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{Status: "ok"})
+}
+
+// readyz reports whether this instance can serve traffic: the database
+// must answer and its schema must be fully migrated
+func readyz(c *gin.Context) {
+	if shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, models.HealthResponse{Status: "shutting_down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	ready := true
+	checks := map[string]string{"database": "ok", "migrations": "ok"}
+	if err := db.PingContext(ctx); err != nil {
+		ready = false
+		checks["database"] = err.Error()
+	}
+	if version, err := schemaVersion(ctx, db); err != nil {
+		ready = false
+		checks["migrations"] = err.Error()
+	} else if version < latestMigration() {
+		ready = false
+		checks["migrations"] = fmt.Sprintf("at version %d, want %d", version, latestMigration())
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, models.HealthResponse{Status: "not_ready", Checks: checks})
+		return
+	}
+	c.JSON(http.StatusOK, models.HealthResponse{Status: "ready", Checks: checks})
+}
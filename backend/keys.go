@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"auction-system/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minHMACSecret is the shortest HS256 secret we accept, matching the
+// output size of SHA-256
+const minHMACSecret = 32
+
+// clockSkew is how far exp, nbf and iat may be off between servers
+const clockSkew = 30 * time.Second
+
+// tokenKeys signs and verifies access tokens; set by setupRouter
+var tokenKeys *keyring
+
+// keyring holds the key new tokens are signed with and every key, by kid,
+// that tokens are still accepted from
+type keyring struct {
+	method     jwt.SigningMethod
+	signingKID string
+	signingKey any
+	verifyKeys map[string]any
+	issuer     string
+	audience   string
+}
+
+// loadKeyring builds the keyring described by the auth configuration.
+// With HS256 and no keys configured a random secret is generated, which
+// is fine for development but logs everyone out on restart.
+func loadKeyring(cfg *config.AuthConfig) (*keyring, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	switch method {
+	case jwt.SigningMethodHS256, jwt.SigningMethodRS256, jwt.SigningMethodEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q (use HS256, RS256 or EdDSA)", cfg.Algorithm)
+	}
+
+	k := &keyring{
+		method:     method,
+		signingKID: cfg.SigningKeyID,
+		verifyKeys: map[string]any{},
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+	}
+
+	for kid, path := range cfg.KeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading JWT key %q: %w", kid, err)
+		}
+		sign, verify, err := parseKey(method, data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing JWT key %q from %s: %w", kid, path, err)
+		}
+		k.verifyKeys[kid] = verify
+		if kid == cfg.SigningKeyID {
+			k.signingKey = sign
+		}
+	}
+
+	if cfg.Secret != "" {
+		if method != jwt.SigningMethodHS256 {
+			return nil, errors.New("JWT_SECRET can only be used with HS256")
+		}
+		if _, dup := k.verifyKeys[cfg.SigningKeyID]; dup {
+			return nil, fmt.Errorf("JWT key %q is set by both JWT_SECRET and JWT_KEY_FILES", cfg.SigningKeyID)
+		}
+		if len(cfg.Secret) < minHMACSecret {
+			return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes", minHMACSecret)
+		}
+		k.signingKey = []byte(cfg.Secret)
+		k.verifyKeys[cfg.SigningKeyID] = []byte(cfg.Secret)
+	}
+
+	if len(k.verifyKeys) == 0 && method == jwt.SigningMethodHS256 {
+		secret := make([]byte, minHMACSecret)
+		rand.Read(secret)
+		k.signingKey = secret
+		k.verifyKeys[cfg.SigningKeyID] = secret
+		slog.Warn("no JWT key configured, using a random secret; tokens will not survive a restart")
+	}
+
+	if k.signingKey == nil {
+		return nil, fmt.Errorf("no private key configured for JWT_SIGNING_KEY_ID %q", cfg.SigningKeyID)
+	}
+	return k, nil
+}
+
+// parseKey decodes a key file for the given algorithm. Asymmetric keys may
+// be public only, in which case they verify but cannot sign.
+func parseKey(method jwt.SigningMethod, data []byte) (sign, verify any, err error) {
+	switch method {
+	case jwt.SigningMethodHS256:
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < minHMACSecret {
+			return nil, nil, fmt.Errorf("HMAC secret must be at least %d bytes", minHMACSecret)
+		}
+		return secret, secret, nil
+	case jwt.SigningMethodRS256:
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			return private, &private.PublicKey, nil
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		return nil, public, err
+	case jwt.SigningMethodEdDSA:
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			return private, private.(crypto.Signer).Public(), nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(data)
+		return nil, public, err
+	}
+	return nil, nil, fmt.Errorf("unsupported algorithm %s", method.Alg())
+}
+
+// sign issues a token with the current signing key, stamping the issuer,
+// audience and kid
+func (k *keyring) sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"] = k.issuer
+	claims["aud"] = k.audience
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// parse verifies a token strictly: only the configured algorithm, a known
+// kid, the expected issuer and audience, and exp/nbf/iat all present and
+// within clockSkew. The parser only requires exp, so nbf and iat are
+// checked for here; it validates them whenever they are set.
+func (k *keyring) parse(tokenStr string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, k.keyFor,
+		jwt.WithValidMethods([]string{k.method.Alg()}),
+		jwt.WithIssuer(k.issuer),
+		jwt.WithAudience(k.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, err
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	for _, name := range []string{"nbf", "iat"} {
+		if _, ok := claims[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", jwt.ErrTokenRequiredClaimMissing, name)
+		}
+	}
+	return claims, nil
+}
+
+func (k *keyring) keyFor(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
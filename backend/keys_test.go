@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-system/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testHMACSecret = "0123456789abcdef0123456789abcdef"
+
+// writePEM stores a private key as PKCS#8 PEM and returns its path
+func writePEM(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testKeyring loads a keyring for alg with a single key under kid "k1"
+func testKeyring(t *testing.T, alg string) *keyring {
+	t.Helper()
+	cfg := &config.AuthConfig{Algorithm: alg, Issuer: "auction-system", Audience: "auction-api", SigningKeyID: "k1"}
+	switch alg {
+	case "HS256":
+		cfg.Secret = testHMACSecret
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.KeyFiles = map[string]string{"k1": writePEM(t, key)}
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.KeyFiles = map[string]string{"k1": writePEM(t, key)}
+	}
+	k, err := loadKeyring(cfg)
+	if err != nil {
+		t.Fatalf("loadKeyring(%s): %v", alg, err)
+	}
+	return k
+}
+
+// validClaims are claims that pass every check
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{"sub": "42", "iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	for _, alg := range []string{"HS256", "RS256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			k := testKeyring(t, alg)
+			token, err := k.sign(validClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := k.parse(token)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if claims["sub"] != "42" || claims["iss"] != "auction-system" || claims["aud"] != "auction-api" {
+				t.Errorf("unexpected claims %v", claims)
+			}
+		})
+	}
+}
+
+func TestKeyringRejects(t *testing.T) {
+	k := testKeyring(t, "HS256")
+	signWith := func(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	stamped := func(edit func(jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		claims["iss"], claims["aud"] = "auction-system", "auction-api"
+		edit(claims)
+		return claims
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"algorithm other than the kid's key", signWith(jwt.SigningMethodHS384, "k1", []byte(testHMACSecret), stamped(func(jwt.MapClaims) {}))},
+		{"asymmetric algorithm", signWith(jwt.SigningMethodEdDSA, "k1", edKey, stamped(func(jwt.MapClaims) {}))},
+		{"unknown kid", signWith(jwt.SigningMethodHS256, "k2", []byte(testHMACSecret), stamped(func(jwt.MapClaims) {}))},
+		{"wrong secret", signWith(jwt.SigningMethodHS256, "k1", []byte(strings.Repeat("x", 32)), stamped(func(jwt.MapClaims) {}))},
+		{"wrong issuer", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) { c["iss"] = "someone-else" }))},
+		{"wrong audience", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) { c["aud"] = "other-api" }))},
+		{"expired beyond the skew", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix()
+		}))},
+		{"missing exp", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) { delete(c, "exp") }))},
+		{"missing nbf", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) { delete(c, "nbf") }))},
+		{"missing iat", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) { delete(c, "iat") }))},
+		{"not valid yet", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) {
+			c["nbf"] = time.Now().Add(clockSkew + time.Minute).Unix()
+		}))},
+		{"issued in the future", signWith(jwt.SigningMethodHS256, "k1", []byte(testHMACSecret), stamped(func(c jwt.MapClaims) {
+			c["iat"] = time.Now().Add(clockSkew + time.Minute).Unix()
+		}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := k.parse(tt.token); err == nil {
+				t.Error("parse accepted the token")
+			}
+		})
+	}
+}
+
+func TestKeyringAcceptsExpiryWithinSkew(t *testing.T) {
+	k := testKeyring(t, "HS256")
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+	token, err := k.sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.parse(token); err != nil {
+		t.Errorf("token expired within the skew was refused: %v", err)
+	}
+}
+
+func TestKeyringVerifiesWithRetiredPublicKey(t *testing.T) {
+	// A retired RS256 key kept as its public half still verifies
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPath := filepath.Join(t.TempDir(), "old.pem")
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	current, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := loadKeyring(&config.AuthConfig{Algorithm: "RS256", Issuer: "auction-system", Audience: "auction-api",
+		SigningKeyID: "new", KeyFiles: map[string]string{"new": writePEM(t, current), "old": publicPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &keyring{method: jwt.SigningMethodRS256, signingKID: "old", signingKey: key, issuer: k.issuer, audience: k.audience}
+	token, err := old.sign(validClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.parse(token); err != nil {
+		t.Errorf("token from the retired key was refused: %v", err)
+	}
+}
+
+func TestLoadKeyringRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+	}{
+		{"unsupported algorithm", config.AuthConfig{Algorithm: "none", SigningKeyID: "k1"}},
+		{"short secret", config.AuthConfig{Algorithm: "HS256", SigningKeyID: "k1", Secret: "short"}},
+		{"secret with RS256", config.AuthConfig{Algorithm: "RS256", SigningKeyID: "k1", Secret: testHMACSecret}},
+		{"no RS256 signing key", config.AuthConfig{Algorithm: "RS256", SigningKeyID: "k1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadKeyring(&tt.cfg); err == nil {
+				t.Error("loadKeyring accepted the configuration")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// lifecycleInterval is how often scheduled auctions are opened and ended
+// ones closed
+const lifecycleInterval = 30 * time.Second
+
+// advanceAuctions runs one pass of the auction lifecycle
+func advanceAuctions(ctx context.Context) error {
+	if err := openStartedAuctions(ctx); err != nil {
+		return err
+	}
+	return closeEndedAuctions(ctx)
+}
+
+// openStartedAuctions makes upcoming auctions whose start time has passed
+// active, so they take bids
+func openStartedAuctions(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "auction.open")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "opening auctions failed")
+		}
+		span.End()
+	}()
+
+	res, err := db.ExecContext(ctx,
+		"UPDATE items SET status = 'active' WHERE status = 'upcoming' AND start_time <= NOW() AND session_id IS NULL")
+	if err != nil {
+		return err
+	}
+	opened, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	auctionsOpenedTotal.Add(float64(opened))
+	span.SetAttributes(attribute.Int64("auction.opened_count", opened))
+	if opened > 0 {
+		slog.InfoContext(ctx, "opened scheduled auctions", "count", opened)
+	}
+	return nil
+}
+
+// closeEndedAuctions settles every active auction whose end time has
+// passed, marking it sold when it received at least one bid and allocating
+// the units of multi-unit auctions among their bids. Unsold auctions with
+// relists left are listed again.
+func closeEndedAuctions(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "auction.close")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "closing auctions failed")
+		}
+		span.End()
+	}()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE items i
+		SET status = CASE WHEN EXISTS (SELECT 1 FROM bids b WHERE b.item_id = i.id) THEN 'sold' ELSE 'unsold' END,
+		    closed_at = NOW()
+		WHERE i.status = 'active' AND i.end_time <= NOW()
+		RETURNING i.id, i.status, i.format, i.quantity, COALESCE(i.clearing, ''), i.relist_count < i.relist_max
+	`)
+	if err != nil {
+		return err
+	}
+	type closedItem struct {
+		id, quantity              int
+		outcome, format, clearing string
+		relist                    bool
+	}
+	var items []closedItem
+	for rows.Next() {
+		var it closedItem
+		if err := rows.Scan(&it.id, &it.outcome, &it.format, &it.quantity, &it.clearing, &it.relist); err != nil {
+			rows.Close()
+			return err
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	relisted := map[int]int{}
+	for _, it := range items {
+		if it.format == formatMultiUnit && it.outcome == "sold" {
+			if err := allocateUnits(ctx, tx, it.id, it.quantity, it.clearing); err != nil {
+				return err
+			}
+		}
+		if it.outcome == "unsold" && it.relist {
+			if relisted[it.id], err = relistItem(ctx, tx, it.id); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for from, id := range relisted {
+		slog.InfoContext(ctx, "unsold auction relisted", "item_id", from, "relisted_as", id)
+	}
+
+	closed := len(items)
+	for _, it := range items {
+		auctionsClosedTotal.WithLabelValues(it.outcome).Inc()
+	}
+	span.SetAttributes(attribute.Int("auction.closed_count", closed))
+	if closed > 0 {
+		slog.InfoContext(ctx, "closed ended auctions", "count", closed)
+	}
+
+	var open int
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM items WHERE status = 'active' AND start_time <= NOW() AND end_time > NOW()").Scan(&open); err != nil {
+		return err
+	}
+	activeAuctions.Set(float64(open))
+	return nil
+}
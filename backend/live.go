@@ -0,0 +1,701 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Calls an auctioneer makes on the open lot, in order. A new bid takes the
+// lot back to callOpen, so it must be accepted and called again.
+const (
+	callOpen       = "open"
+	callAccepted   = "accepted"
+	callGoingOnce  = "going_once"
+	callGoingTwice = "going_twice"
+)
+
+// createLiveSession schedules a live auction run by the calling auctioneer.
+// Sellers then add their drafts to it as lots.
+func createLiveSession(c *gin.Context) {
+	var req struct {
+		Title        string    `json:"title" binding:"required,notblank,max=200"`
+		StartsAt     time.Time `json:"starts_at" binding:"required,auction_start"`
+		BidIncrement float64   `json:"bid_increment" binding:"required,finite,gt=0,lte=99999999.99"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	var id int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO live_sessions (title, auctioneer_id, starts_at, bid_increment)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, req.Title, accountID(c), req.StartsAt, req.BidIncrement).Scan(&id)
+	if err != nil {
+		respondDBError(c, err, "Could not create session")
+		return
+	}
+	session, err := loadLiveSession(ctx, fmt.Sprint(id))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch session")
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// listLiveSessions returns the sessions that have not closed yet, soonest
+// first, without their lots
+func listLiveSessions(c *gin.Context) {
+	rows, err := db.QueryContext(c.Request.Context(), `
+		SELECT `+liveSessionSQL+`
+		FROM live_sessions s JOIN accounts a ON a.id = s.auctioneer_id
+		WHERE s.status <> 'closed'
+		ORDER BY s.starts_at
+	`)
+	if err != nil {
+		respondDBError(c, err, "Could not fetch sessions")
+		return
+	}
+	defer rows.Close()
+	sessions := []models.LiveSession{}
+	for rows.Next() {
+		var s models.LiveSession
+		if err := scanLiveSession(rows, &s); err != nil {
+			respondDBError(c, err, "Could not fetch sessions")
+			return
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		respondDBError(c, err, "Could not fetch sessions")
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// getLiveSession returns a session with its catalogue of lots in order
+func getLiveSession(c *gin.Context) {
+	session, err := loadLiveSession(c.Request.Context(), c.Param("sessionId"))
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not fetch session")
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// streamLiveSession follows a session as server-sent events, sending a
+// "session" event on connect and after every bid or call, until it closes
+func streamLiveSession(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := c.Param("sessionId")
+	session, err := loadLiveSession(ctx, sessionID)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not fetch session")
+		return
+	}
+	streamUpdates(c, "session", session, session.Status == "closed", func() (any, bool, error) {
+		s, err := loadLiveSession(ctx, sessionID)
+		return s, s.Status == "closed", err
+	})
+}
+
+// liveSessionSQL selects the columns read by scanLiveSession from
+// live_sessions s and its auctioneer a
+const liveSessionSQL = `s.id, s.title, s.auctioneer_id, a.name, s.starts_at, s.bid_increment, s.status, s.current_item_id`
+
+// scanLiveSession reads a row selected with liveSessionSQL
+func scanLiveSession(row interface{ Scan(...any) error }, s *models.LiveSession) error {
+	return row.Scan(&s.ID, &s.Title, &s.AuctioneerID, &s.Auctioneer, &s.StartsAt, &s.BidIncrement, &s.Status, &s.CurrentItemID)
+}
+
+// loadLiveSession reads a session and its lots
+func loadLiveSession(ctx context.Context, sessionID string) (models.LiveSession, error) {
+	var s models.LiveSession
+	err := scanLiveSession(db.QueryRowContext(ctx, `
+		SELECT `+liveSessionSQL+`
+		FROM live_sessions s JOIN accounts a ON a.id = s.auctioneer_id
+		WHERE s.id = $1
+	`, sessionID), &s)
+	if err != nil {
+		return s, err
+	}
+	s.Lots, err = queryItems(ctx, "i.session_id = $1", "i.session_position", s.ID)
+	return s, err
+}
+
+// addSessionLot lets a seller put one of their drafts up as the next lot
+// of a session. The lot is announced as upcoming from the session's start
+// and takes absentee bids until the auctioneer opens it.
+func addSessionLot(c *gin.Context) {
+	var req struct {
+		ItemID int `json:"item_id" binding:"required,gt=0"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not add lot")
+		return
+	}
+	defer tx.Rollback()
+
+	// Locking the session keeps lot positions unique
+	var sessionID int
+	var sessionStatus string
+	var startsAt time.Time
+	err = tx.QueryRowContext(ctx, "SELECT id, status, starts_at FROM live_sessions WHERE id = $1 FOR UPDATE",
+		c.Param("sessionId")).Scan(&sessionID, &sessionStatus, &startsAt)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not add lot")
+		return
+	}
+	if sessionStatus == "closed" {
+		respondError(c, http.StatusConflict, codeConflict, "Session is closed")
+		return
+	}
+
+	var name, description, status, format string
+	var startingPrice float64
+	var sellerID, relistMax int
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, COALESCE(description, ''), starting_price, seller_id, COALESCE(status, 'active'), format, relist_max
+		FROM items WHERE id = $1 FOR UPDATE
+	`, req.ItemID).Scan(&name, &description, &startingPrice, &sellerID, &status, &format, &relistMax)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Auction not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not add lot")
+		return
+	}
+	if sellerID != accountID(c) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the seller can add this auction to a session")
+		return
+	}
+	if status != "draft" || format != formatEnglish {
+		respondError(c, http.StatusConflict, codeConflict, "Only English-format drafts can be added to a session")
+		return
+	}
+	// The auctioneer hammers lots down rather than the lifecycle closing
+	// them, so they are never relisted
+	if relistMax > 0 {
+		respondError(c, http.StatusConflict, codeConflict, "Drafts with relist rules cannot be added to a session",
+			models.FieldError{Field: "item_id", Message: "has relist rules, which session lots do not support"})
+		return
+	}
+	if details := listingProblems(name, description, startingPrice); len(details) > 0 {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Draft is not ready to publish", details...)
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE items
+		SET session_id = $2, status = 'upcoming', start_time = $3,
+		    session_position = (SELECT COALESCE(MAX(session_position), 0) + 1 FROM items WHERE session_id = $2)
+		WHERE id = $1
+	`, req.ItemID, sessionID, startsAt)
+	if err != nil {
+		respondDBError(c, err, "Could not add lot")
+		return
+	}
+	audit(c, tx, auditEvent{action: auditItemPublished, targetType: auditTargetItem, targetID: req.ItemID,
+		diff: auditDiff(map[string]any{"status": "draft"},
+			map[string]any{"status": "upcoming", "start_time": startsAt, "session_id": sessionID})})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not add lot")
+		return
+	}
+
+	item, err := loadItem(ctx, fmt.Sprint(req.ItemID))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// placeAbsenteeBid records the most a bidder will pay for a session lot
+// before it opens. Bidding on their behalf is left to executeAbsenteeBids;
+// a second absentee bid on the same lot replaces the first.
+func placeAbsenteeBid(c *gin.Context) {
+	var req struct {
+		MaxAmount float64 `json:"max_amount" binding:"required,finite,gt=0,lte=99999999.99"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	bidderID := accountID(c)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not place absentee bid")
+		return
+	}
+	defer tx.Rollback()
+
+	// The lock keeps the auctioneer from opening the lot in between
+	var sellerID int
+	var status string
+	var startingPrice float64
+	var live bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT seller_id, COALESCE(status, 'active'), starting_price, session_id IS NOT NULL
+		FROM items WHERE id = $1 AND status IS DISTINCT FROM 'draft' FOR UPDATE
+	`, c.Param("itemId")).Scan(&sellerID, &status, &startingPrice, &live)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Item not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not place absentee bid")
+		return
+	}
+	if sellerID == bidderID {
+		respondError(c, http.StatusForbidden, codeForbidden, "Cannot bid on your own item")
+		return
+	}
+	if !live {
+		respondError(c, http.StatusConflict, codeConflict, "Absentee bids are only taken for lots of a live session")
+		return
+	}
+	if status != "upcoming" {
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Lot has already been opened")
+		return
+	}
+	if req.MaxAmount < startingPrice {
+		respondError(c, http.StatusUnprocessableEntity, codeBidTooLow, "Bid must be at least the starting price",
+			models.FieldError{Field: "max_amount", Message: fmt.Sprintf("must be at least %.2f", startingPrice)})
+		return
+	}
+	if req.MaxAmount > accountPolicy.UnverifiedBidLimit {
+		verified, err := emailVerified(ctx, bidderID)
+		if err != nil {
+			respondDBError(c, err, "Could not place absentee bid")
+			return
+		}
+		if !verified {
+			respondError(c, http.StatusForbidden, codeEmailUnverified,
+				"Verify your email address to bid above the limit for unverified accounts",
+				models.FieldError{Field: "max_amount", Message: fmt.Sprintf("must be at most %.2f until your email is verified", accountPolicy.UnverifiedBidLimit)})
+			return
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO absentee_bids (item_id, bidder_id, max_amount) VALUES ($1, $2, $3)
+		ON CONFLICT (item_id, bidder_id) DO UPDATE SET max_amount = EXCLUDED.max_amount, placed_at = NOW()
+	`, c.Param("itemId"), bidderID, req.MaxAmount)
+	if err != nil {
+		respondDBError(c, err, "Could not place absentee bid")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not place absentee bid")
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Absentee bid recorded; it will be placed for you when the lot opens"})
+}
+
+// placeLiveBid takes a bid on the open lot of a session. Each bid must be
+// at least the session's increment above the last, and sends the
+// auctioneer's calls back to the start.
+func placeLiveBid(c *gin.Context, itemID string, bidderID int, amount float64) {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	var startingPrice, increment float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT i.status, i.starting_price, s.bid_increment
+		FROM items i JOIN live_sessions s ON s.id = i.session_id
+		WHERE i.id = $1 FOR UPDATE OF i
+	`, itemID).Scan(&status, &startingPrice, &increment)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if status != "active" {
+		bidRejected(bidRejectedClosed)
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Lot is no longer open")
+		return
+	}
+	minimum, _, err := liveMinimum(ctx, tx, itemID, startingPrice, increment)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if amount < minimum {
+		bidRejected(bidRejectedTooLow)
+		respondError(c, http.StatusUnprocessableEntity, codeBidTooLow, "Bid must be at least the asking price",
+			models.FieldError{Field: "bid_amount", Message: fmt.Sprintf("must be at least %.2f", minimum)})
+		return
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO bids (item_id, bidder_id, bid_amount) VALUES ($1, $2, $3)", itemID, bidderID, amount); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE items SET live_state = $2 WHERE id = $1", itemID, callOpen); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if err := executeAbsenteeBids(ctx, tx, itemID, startingPrice, increment); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	bidAccepted()
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Bid placed"})
+}
+
+// liveMinimum returns the asking price of an open lot and who holds the
+// highest bid, if anyone: the starting price before the first bid, then
+// the increment above the highest
+func liveMinimum(ctx context.Context, tx *sql.Tx, itemID any, startingPrice, increment float64) (float64, int, error) {
+	var high float64
+	var bidderID int
+	err := tx.QueryRowContext(ctx,
+		"SELECT bid_amount, bidder_id FROM bids WHERE item_id = $1 ORDER BY bid_amount DESC, bid_time LIMIT 1",
+		itemID).Scan(&high, &bidderID)
+	if err == sql.ErrNoRows {
+		return startingPrice, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return high + increment, bidderID, nil
+}
+
+// executeAbsenteeBids bids for the absentee bidder with the highest limit
+// who is not already winning, if the limit reaches the asking price. Like
+// an auction house clerk it bids only as high as needed: one increment
+// over the next absentee bidder's limit, but at most its own limit.
+// Earlier absentee bids win ties.
+func executeAbsenteeBids(ctx context.Context, tx *sql.Tx, itemID any, startingPrice, increment float64) error {
+	minimum, highBidder, err := liveMinimum(ctx, tx, itemID, startingPrice, increment)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT bidder_id, max_amount FROM absentee_bids
+		WHERE item_id = $1 AND bidder_id <> $2 AND max_amount >= $3
+		ORDER BY max_amount DESC, placed_at
+		LIMIT 2
+	`, itemID, highBidder, minimum)
+	if err != nil {
+		return err
+	}
+	type absentee struct {
+		bidderID int
+		max      float64
+	}
+	var top []absentee
+	for rows.Next() {
+		var a absentee
+		if err := rows.Scan(&a.bidderID, &a.max); err != nil {
+			rows.Close()
+			return err
+		}
+		top = append(top, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(top) == 0 {
+		return err
+	}
+
+	price := minimum
+	if len(top) == 2 {
+		price = max(price, top[1].max+increment)
+	}
+	price = min(price, top[0].max)
+	_, err = tx.ExecContext(ctx, "INSERT INTO bids (item_id, bidder_id, bid_amount) VALUES ($1, $2, $3)",
+		itemID, top[0].bidderID, price)
+	return err
+}
+
+// sessionConsole carries out the auctioneer's calls: open a lot (the one
+// given or the next in the catalogue), accept its current bid, call going
+// once and going twice, and hammer it down. Calls must come in that order;
+// a bid in between sends the lot back to open. A session with no lot left
+// to sell can be ended. Only the session's auctioneer or an admin may run it.
+func sessionConsole(c *gin.Context) {
+	var req struct {
+		Action string `json:"action" binding:"required,oneof=open accept going_once going_twice hammer end"`
+		ItemID int    `json:"item_id" binding:"omitempty,gt=0"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not update session")
+		return
+	}
+	defer tx.Rollback()
+
+	var sessionID, auctioneerID int
+	var status string
+	var increment float64
+	var currentID sql.NullInt64
+	var started bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, auctioneer_id, status, bid_increment, current_item_id, starts_at <= NOW()
+		FROM live_sessions WHERE id = $1 FOR UPDATE
+	`, c.Param("sessionId")).Scan(&sessionID, &auctioneerID, &status, &increment, &currentID, &started)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not update session")
+		return
+	}
+	if auctioneerID != accountID(c) && !hasRole(c, roleAdmin) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the session's auctioneer can run it")
+		return
+	}
+	if status == "closed" {
+		respondError(c, http.StatusConflict, codeConflict, "Session is closed")
+		return
+	}
+
+	// No lot can be opened, and so none hammered, before the advertised
+	// start; a session left without lots can be ended at any time
+	if !started && req.Action != "end" {
+		respondError(c, http.StatusConflict, codeConflict, "Session has not started yet")
+		return
+	}
+
+	var outcome string
+	switch req.Action {
+	case "open":
+		if !openLot(c, tx, sessionID, currentID, req.ItemID, increment) {
+			return
+		}
+	case "end":
+		if !endSession(c, tx, sessionID) {
+			return
+		}
+	default:
+		var ok bool
+		if outcome, ok = callLot(c, tx, sessionID, currentID, req.Action); !ok {
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not update session")
+		return
+	}
+	if outcome != "" {
+		auctionsClosedTotal.WithLabelValues(outcome).Inc()
+		slog.InfoContext(ctx, "lot hammered", "session_id", sessionID, "item_id", currentID.Int64, "outcome", outcome)
+	}
+
+	session, err := loadLiveSession(ctx, fmt.Sprint(sessionID))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch session")
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// openLot puts a lot on the block and places the absentee bids it has
+// collected. It reports whether a response is still to be written.
+func openLot(c *gin.Context, tx *sql.Tx, sessionID int, currentID sql.NullInt64, itemID int, increment float64) bool {
+	ctx := c.Request.Context()
+	if currentID.Valid {
+		var open bool
+		err := tx.QueryRowContext(ctx, "SELECT status = 'active' FROM items WHERE id = $1", currentID.Int64).Scan(&open)
+		if err != nil {
+			respondDBError(c, err, "Could not open lot")
+			return false
+		}
+		if open {
+			respondError(c, http.StatusConflict, codeConflict, "Hammer the current lot down first")
+			return false
+		}
+	}
+
+	// Without an item_id the next lot in the catalogue opens
+	var lotID int
+	var startingPrice float64
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, starting_price FROM items
+		WHERE session_id = $1 AND status = 'upcoming' AND ($2 = 0 OR id = $2)
+		ORDER BY session_position LIMIT 1 FOR UPDATE
+	`, sessionID, itemID).Scan(&lotID, &startingPrice)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusConflict, codeConflict, "No lot of this session is waiting to open")
+		return false
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not open lot")
+		return false
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE items SET status = 'active', start_time = NOW(), live_state = $2 WHERE id = $1", lotID, callOpen)
+	if err != nil {
+		respondDBError(c, err, "Could not open lot")
+		return false
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE live_sessions SET status = 'running', current_item_id = $2 WHERE id = $1", sessionID, lotID)
+	if err != nil {
+		respondDBError(c, err, "Could not open lot")
+		return false
+	}
+	if err := executeAbsenteeBids(ctx, tx, lotID, startingPrice, increment); err != nil {
+		respondDBError(c, err, "Could not open lot")
+		return false
+	}
+	return true
+}
+
+// callLot applies an auctioneer's call to the open lot. Hammering it down
+// sells it to the highest bid, or passes it without one, and closes the
+// session after its last lot. It returns the outcome of a hammered lot and
+// whether a response is still to be written.
+func callLot(c *gin.Context, tx *sql.Tx, sessionID int, currentID sql.NullInt64, action string) (string, bool) {
+	ctx := c.Request.Context()
+	var status, call string
+	var bids int
+	if currentID.Valid {
+		err := tx.QueryRowContext(ctx, `
+			SELECT i.status, COALESCE(i.live_state, ''), (SELECT COUNT(*) FROM bids b WHERE b.item_id = i.id)
+			FROM items i WHERE i.id = $1 FOR UPDATE
+		`, currentID.Int64).Scan(&status, &call, &bids)
+		if err != nil {
+			respondDBError(c, err, "Could not update lot")
+			return "", false
+		}
+	}
+	if status != "active" {
+		respondError(c, http.StatusConflict, codeConflict, "No lot is open")
+		return "", false
+	}
+
+	var next, refusal string
+	switch action {
+	case "accept":
+		next = callAccepted
+		if bids == 0 {
+			refusal = "There is no bid to accept"
+		} else if call != callOpen {
+			refusal = "The current bid has already been accepted"
+		}
+	case "going_once":
+		next = callGoingOnce
+		if call != callAccepted && (call != callOpen || bids > 0) {
+			refusal = "Accept the current bid before going once"
+		}
+	case "going_twice":
+		next = callGoingTwice
+		if call != callGoingOnce {
+			refusal = "Call going once before going twice"
+		}
+	case "hammer":
+		if call != callGoingTwice {
+			refusal = "Call going twice before the hammer"
+		}
+	}
+	if refusal != "" {
+		respondError(c, http.StatusConflict, codeConflict, refusal)
+		return "", false
+	}
+	if next != "" {
+		if _, err := tx.ExecContext(ctx, "UPDATE items SET live_state = $2 WHERE id = $1", currentID.Int64, next); err != nil {
+			respondDBError(c, err, "Could not update lot")
+			return "", false
+		}
+		return "", true
+	}
+
+	outcome := "unsold"
+	if bids > 0 {
+		outcome = "sold"
+	}
+	_, err := tx.ExecContext(ctx, `
+		UPDATE items SET status = $2, live_state = NULL, end_time = NOW(), closed_at = NOW() WHERE id = $1
+	`, currentID.Int64, outcome)
+	if err != nil {
+		respondDBError(c, err, "Could not update lot")
+		return "", false
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE live_sessions SET current_item_id = NULL WHERE id = $1", sessionID); err != nil {
+		respondDBError(c, err, "Could not update lot")
+		return "", false
+	}
+	if _, err := closeFinishedSession(ctx, tx, sessionID); err != nil {
+		respondDBError(c, err, "Could not update lot")
+		return "", false
+	}
+	return outcome, true
+}
+
+// endSession closes a session that has no lot left to sell, such as one
+// whose remaining lots were all cancelled. It reports whether a response
+// is still to be written.
+func endSession(c *gin.Context, tx *sql.Tx, sessionID int) bool {
+	closed, err := closeFinishedSession(c.Request.Context(), tx, sessionID)
+	if err != nil {
+		respondDBError(c, err, "Could not end session")
+		return false
+	}
+	if !closed {
+		respondError(c, http.StatusConflict, codeConflict, "Session still has lots to sell")
+		return false
+	}
+	return true
+}
+
+// closeFinishedSession closes a session once none of its lots is upcoming
+// or on the block, and reports whether it did
+func closeFinishedSession(ctx context.Context, ex execer, sessionID int) (bool, error) {
+	res, err := ex.ExecContext(ctx, `
+		UPDATE live_sessions SET status = 'closed', current_item_id = NULL
+		WHERE id = $1 AND status <> 'closed'
+		  AND NOT EXISTS (SELECT 1 FROM items WHERE session_id = $1 AND status IN ('upcoming', 'active'))
+	`, sessionID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
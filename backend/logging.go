@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveKeys are log attribute keys whose values are never written out
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"password_hash": true,
+	"token":         true,
+	"refresh_token": true,
+	"authorization": true,
+	"secret":        true,
+	"body":          true,
+}
+
+// setupLogging installs a JSON slog logger as the process default. The
+// standard library log package is routed through it as well.
+func setupLogging() {
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})
+	slog.SetDefault(slog.New(requestContextHandler{handler}))
+}
+
+// redactAttr hides secrets and masks email addresses in every log line
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case sensitiveKeys[key]:
+		return slog.String(a.Key, "[REDACTED]")
+	case key == "email" || strings.HasSuffix(key, "_email"):
+		return slog.String(a.Key, maskEmail(a.Value.String()))
+	}
+	return a
+}
+
+// maskEmail keeps enough of an address to correlate log lines without
+// recording it: alice@example.com becomes a***@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return "[REDACTED]"
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// requestContextHandler adds the request and trace IDs carried by the
+// context to every record logged with one of the slog *Context functions
+type requestContextHandler struct {
+	slog.Handler
+}
+
+func (h requestContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestIDFrom(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id := traceIDFrom(ctx); id != "" {
+		r.AddAttrs(slog.String("trace_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestContextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestContextHandler) WithGroup(name string) slog.Handler {
+	return requestContextHandler{h.Handler.WithGroup(name)}
+}
+
+// accessLog writes one structured line per request with the caller's
+// identity and roles, the route pattern, status and latency
+func accessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	status := c.Writer.Status()
+	attrs := []slog.Attr{
+		slog.String("method", c.Request.Method),
+		slog.String("route", c.FullPath()),
+		slog.String("path", c.Request.URL.Path),
+		slog.Int("status", status),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		slog.Int("bytes", c.Writer.Size()),
+		slog.String("client_ip", c.ClientIP()),
+	}
+	if id := accountID(c); id != 0 {
+		attrs = append(attrs, slog.Int("account_id", id), slog.Any("roles", accountRoles(c)))
+	}
+	if len(c.Errors) > 0 {
+		attrs = append(attrs, slog.String("errors", c.Errors.String()))
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+}
+
+// accountID returns the authenticated account's ID, or 0 for anonymous
+// requests
+func accountID(c *gin.Context) int {
+	return c.GetInt("account_id")
+}
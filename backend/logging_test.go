@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		email, want string
+	}{
+		{"alice@example.com", "a***@example.com"},
+		{"a@example.com", "a***@example.com"},
+		{"bob+bids@mail.example.com", "b***@mail.example.com"},
+		{`"odd@name"@example.com`, `"***@example.com`},
+		{"@example.com", "[REDACTED]"},
+		{"not-an-address", "[REDACTED]"},
+		{"", "[REDACTED]"},
+	}
+	for _, tt := range tests {
+		if got := maskEmail(tt.email); got != tt.want {
+			t.Errorf("maskEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestRedactAttr(t *testing.T) {
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want string
+	}{
+		{"password", slog.String("password", "hunter22"), "[REDACTED]"},
+		{"key case is ignored", slog.String("Authorization", "Bearer abc"), "[REDACTED]"},
+		{"refresh token", slog.String("refresh_token", "abc"), "[REDACTED]"},
+		{"mail body", slog.String("body", "Your reset code is 123456"), "[REDACTED]"},
+		{"non-string secret", slog.Int("secret", 42), "[REDACTED]"},
+		{"email", slog.String("email", "alice@example.com"), "a***@example.com"},
+		{"email suffix", slog.String("to_email", "bob@example.com"), "b***@example.com"},
+		{"other keys pass through", slog.String("subject", "Outbid"), "Outbid"},
+		{"key containing a sensitive word", slog.String("token_type", "bearer"), "bearer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactAttr(nil, tt.attr)
+			if got.Key != tt.attr.Key || got.Value.String() != tt.want {
+				t.Errorf("redactAttr(%s) = %s, want %s=%s", tt.attr, got, tt.attr.Key, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// lotItemRequest is one catalogued item of a new lot
+type lotItemRequest struct {
+	Name        string   `json:"name" binding:"required,notblank,max=200"`
+	Description string   `json:"description" binding:"max=5000"`
+	ImageURLs   []string `json:"image_urls" binding:"max=20,dive,url,max=2000"`
+}
+
+// insertLotItems catalogues the items of a lot in the order given
+func insertLotItems(ctx context.Context, tx *sql.Tx, lotID int, entries []lotItemRequest) error {
+	for i, e := range entries {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO lot_items (lot_id, position, name, description, image_urls)
+			VALUES ($1, $2, $3, $4, $5)
+		`, lotID, i+1, e.Name, e.Description, pq.Array(nonNil(e.ImageURLs)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nonNil turns a nil slice into an empty one, since image_urls is NOT NULL
+func nonNil(urls []string) []string {
+	if urls == nil {
+		return []string{}
+	}
+	return urls
+}
+
+// lotItems returns the catalogued items of a listing, in lot order
+func lotItems(ctx context.Context, ex queryer, itemID int) ([]models.LotItem, error) {
+	rows, err := ex.QueryContext(ctx,
+		"SELECT position, name, description, image_urls FROM lot_items WHERE lot_id = $1 ORDER BY position", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []models.LotItem{}
+	for rows.Next() {
+		var it models.LotItem
+		if err := rows.Scan(&it.Position, &it.Name, &it.Description, pq.Array(&it.ImageURLs)); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// splitLot turns an unsold lot into one draft listing per catalogued item,
+// each keeping its description and images and taking an even share of the
+// lot's starting price. The seller can adjust and publish them; the lot
+// itself stays as the record of the failed sale.
+func splitLot(c *gin.Context) {
+	ctx := c.Request.Context()
+	itemID := c.Param("itemId")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not split lot")
+		return
+	}
+	defer tx.Rollback()
+
+	var lotID, sellerID int
+	var status string
+	var startingPrice float64
+	var mergedInto sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, seller_id, COALESCE(status, 'active'), starting_price, merged_into
+		FROM items WHERE id = $1 FOR UPDATE
+	`, itemID).Scan(&lotID, &sellerID, &status, &startingPrice, &mergedInto)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Auction not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not split lot")
+		return
+	}
+	if sellerID != accountID(c) && !hasRole(c, roleAdmin) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the seller or an admin can split this lot")
+		return
+	}
+	if status != "unsold" {
+		respondError(c, http.StatusConflict, codeConflict, "Only unsold lots can be split")
+		return
+	}
+	var split, relisted bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM items WHERE split_from = $1), EXISTS (SELECT 1 FROM items WHERE relisted_from = $1)
+	`, lotID).Scan(&split, &relisted)
+	if err != nil {
+		respondDBError(c, err, "Could not split lot")
+		return
+	}
+	if split || mergedInto.Valid {
+		respondError(c, http.StatusConflict, codeConflict, "Lot has already been split or merged")
+		return
+	}
+	if relisted {
+		respondError(c, http.StatusConflict, codeConflict, "Lot has already been relisted")
+		return
+	}
+	entries, err := lotItems(ctx, tx, lotID)
+	if err != nil {
+		respondDBError(c, err, "Could not split lot")
+		return
+	}
+	if len(entries) < 2 {
+		respondError(c, http.StatusConflict, codeConflict, "Only lots of several items can be split")
+		return
+	}
+
+	// Round the share down to the cent so the parts never cost more than
+	// the whole
+	share := max(math.Floor(startingPrice*100/float64(len(entries)))/100, 0.01)
+	var ids []int
+	for _, e := range entries {
+		var id int
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO items (name, description, starting_price, seller_id, start_time, status, split_from)
+			VALUES ($1, $2, $3, $4, NULL, 'draft', $5)
+			RETURNING id
+		`, e.Name, e.Description, share, sellerID, lotID).Scan(&id)
+		if err != nil {
+			respondDBError(c, err, "Could not split lot")
+			return
+		}
+		entry := lotItemRequest{Name: e.Name, Description: e.Description, ImageURLs: e.ImageURLs}
+		if err := insertLotItems(ctx, tx, id, []lotItemRequest{entry}); err != nil {
+			respondDBError(c, err, "Could not split lot")
+			return
+		}
+		audit(c, tx, auditEvent{action: auditItemCreated, targetType: auditTargetItem, targetID: id,
+			diff: auditDiff(nil, map[string]any{"name": e.Name, "description": e.Description,
+				"starting_price": share, "status": "draft", "split_from": lotID})})
+		ids = append(ids, id)
+	}
+	audit(c, tx, auditEvent{action: auditItemSplit, targetType: auditTargetItem, targetID: lotID,
+		details: map[string]string{"items": fmt.Sprint(ids)}})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not split lot")
+		return
+	}
+	slog.InfoContext(ctx, "lot split", "item_id", lotID, "items", len(ids))
+	respondItems(c, ids)
+}
+
+// mergeListings gathers several drafts or unsold listings of one seller
+// into a new draft lot. Their catalogued items, or the listings themselves
+// when they are not lots, become the items of the lot, and its starting
+// price is the sum of theirs. Merged drafts are withdrawn; unsold listings
+// stay as the record of their sale and point at the lot.
+func mergeListings(c *gin.Context) {
+	var req struct {
+		ItemIDs     []int  `json:"item_ids" binding:"required,min=2,max=100,unique,dive,gt=0"`
+		Name        string `json:"name" binding:"required,notblank,max=200"`
+		Description string `json:"description" binding:"max=5000"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+	defer tx.Rollback()
+
+	type source struct {
+		name, description, status, format string
+		sellerID                          int
+		startingPrice                     float64
+		mergedInto                        sql.NullInt64
+		relisted                          bool
+	}
+	sources := map[int]source{}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, name, COALESCE(description, ''), COALESCE(status, 'active'), format, seller_id, starting_price, merged_into,
+		       EXISTS (SELECT 1 FROM items r WHERE r.relisted_from = items.id)
+		FROM items WHERE id = ANY($1) ORDER BY id FOR UPDATE
+	`, pq.Array(req.ItemIDs))
+	if err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+	for rows.Next() {
+		var id int
+		var s source
+		if err := rows.Scan(&id, &s.name, &s.description, &s.status, &s.format, &s.sellerID, &s.startingPrice, &s.mergedInto, &s.relisted); err != nil {
+			rows.Close()
+			respondDBError(c, err, "Could not merge listings")
+			return
+		}
+		sources[id] = s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+
+	sellerID := sources[req.ItemIDs[0]].sellerID
+	var details []models.FieldError
+	var total float64
+	for i, id := range req.ItemIDs {
+		s, ok := sources[id]
+		field := fmt.Sprintf("item_ids[%d]", i)
+		switch {
+		case !ok:
+			respondError(c, http.StatusNotFound, codeNotFound, fmt.Sprintf("Auction %d not found", id))
+			return
+		case s.sellerID != sellerID:
+			details = append(details, models.FieldError{Field: field, Message: "belongs to another seller"})
+		case s.status != "draft" && s.status != "unsold":
+			details = append(details, models.FieldError{Field: field, Message: "must be a draft or unsold"})
+		case s.mergedInto.Valid:
+			details = append(details, models.FieldError{Field: field, Message: "has already been merged"})
+		case s.relisted:
+			details = append(details, models.FieldError{Field: field, Message: "has already been relisted"})
+		case !slices.Contains([]string{formatEnglish, formatDutch, formatSealed}, s.format):
+			details = append(details, models.FieldError{Field: field, Message: "is a " + s.format + " auction"})
+		}
+		total += s.startingPrice
+	}
+	if sellerID != accountID(c) && !hasRole(c, roleAdmin) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the seller or an admin can merge these listings")
+		return
+	}
+	if len(details) > 0 {
+		respondError(c, http.StatusConflict, codeConflict, "These listings cannot be merged", details...)
+		return
+	}
+	if total > maxPrice {
+		respondError(c, http.StatusConflict, codeConflict, "Merged starting price is too high",
+			models.FieldError{Field: "item_ids", Message: fmt.Sprintf("starting prices must add up to at most %.2f", maxPrice)})
+		return
+	}
+
+	var entries []lotItemRequest
+	for _, id := range req.ItemIDs {
+		items, err := lotItems(ctx, tx, id)
+		if err != nil {
+			respondDBError(c, err, "Could not merge listings")
+			return
+		}
+		if len(items) == 0 {
+			entries = append(entries, lotItemRequest{Name: sources[id].name, Description: sources[id].description})
+		}
+		for _, it := range items {
+			entries = append(entries, lotItemRequest{Name: it.Name, Description: it.Description, ImageURLs: it.ImageURLs})
+		}
+	}
+
+	var lotID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO items (name, description, starting_price, seller_id, start_time, status)
+		VALUES ($1, $2, $3, $4, NULL, 'draft')
+		RETURNING id
+	`, req.Name, req.Description, total, sellerID).Scan(&lotID)
+	if err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+	if err := insertLotItems(ctx, tx, lotID, entries); err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE items SET merged_into = $2,
+		       status = CASE WHEN status = 'draft' THEN 'cancelled' ELSE status END,
+		       cancelled_at = CASE WHEN status = 'draft' THEN NOW() ELSE cancelled_at END,
+		       cancelled_by = CASE WHEN status = 'draft' THEN $3 ELSE cancelled_by END,
+		       cancellation_reason = CASE WHEN status = 'draft' THEN $4 ELSE cancellation_reason END
+		WHERE id = ANY($1)
+	`, pq.Array(req.ItemIDs), lotID, accountID(c), fmt.Sprintf("Merged into lot %d", lotID))
+	if err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+	audit(c, tx, auditEvent{action: auditItemCreated, targetType: auditTargetItem, targetID: lotID,
+		diff: auditDiff(nil, map[string]any{"name": req.Name, "description": req.Description,
+			"starting_price": total, "status": "draft", "merged_from": req.ItemIDs})})
+	for _, id := range req.ItemIDs {
+		audit(c, tx, auditEvent{action: auditItemMerged, targetType: auditTargetItem, targetID: id,
+			details: map[string]string{"lot": fmt.Sprint(lotID)}})
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not merge listings")
+		return
+	}
+	slog.InfoContext(ctx, "listings merged", "item_id", lotID, "items", len(req.ItemIDs))
+
+	item, err := loadItem(ctx, fmt.Sprint(lotID))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// respondItems answers with the summaries of the given listings
+func respondItems(c *gin.Context, ids []int) {
+	items := make([]models.ItemSummary, 0, len(ids))
+	for _, id := range ids {
+		item, err := loadItem(c.Request.Context(), fmt.Sprint(id))
+		if err != nil {
+			respondDBError(c, err, "Could not fetch item")
+			return
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"auction-system/config"
+)
+
+// mailMessage is a plain-text email
+type mailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg mailMessage) error
+}
+
+// mailer is the transport selected by MAIL_DRIVER; set by setupRouter
+var mailer Mailer = logMailer{}
+
+// mailSendTimeout bounds a single delivery attempt
+const mailSendTimeout = 30 * time.Second
+
+// pendingMail tracks deliveries still running so shutdown can wait for them
+var pendingMail sync.WaitGroup
+
+// newMailer builds the Mailer named by the configuration
+func newMailer(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if _, err := mail.ParseAddress(cfg.From); err != nil {
+			return nil, fmt.Errorf("invalid MAIL_FROM %q: %w", cfg.From, err)
+		}
+		m := &smtpMailer{addr: net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort), from: cfg.From}
+		if cfg.SMTPUser != "" {
+			m.auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
+		}
+		return m, nil
+	case "file":
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating MAIL_DIR: %w", err)
+		}
+		return &fileMailer{dir: cfg.Dir, from: cfg.From}, nil
+	case "log":
+		return logMailer{}, nil
+	}
+	return nil, fmt.Errorf("unknown MAIL_DRIVER %q (use smtp, file or log)", cfg.Driver)
+}
+
+// sendMailAsync delivers a message in the background so the response time
+// of the calling handler does not depend on whether mail was sent. Failures
+// are logged.
+func sendMailAsync(ctx context.Context, msg mailMessage) {
+	ctx = context.WithoutCancel(ctx)
+	pendingMail.Add(1)
+	go func() {
+		defer pendingMail.Done()
+		ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "sending mail failed", "to_email", msg.To, "subject", msg.Subject, "error", err)
+		}
+	}()
+}
+
+// waitForMail blocks until background deliveries finish or ctx ends
+func waitForMail(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pendingMail.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// formatMessage renders the headers and body of an RFC 5322 message
+func formatMessage(from string, msg mailMessage) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// smtpMailer sends through an SMTP server, using STARTTLS when offered
+type smtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg mailMessage) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	// net/smtp has no context support; run it aside and give up on timeout
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(m.addr, m.auth, from.Address, []string{to.Address}, formatMessage(m.from, msg))
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fileMailer writes each message to an .eml file, for local development
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg mailMessage) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), randomToken(4))
+	return os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o600)
+}
+
+// logMailer notes messages in the log instead of sending them. Bodies
+// carry live reset and verification links, so only the recipient and
+// subject are logged; use the file driver to read them.
+type logMailer struct{}
+
+func (logMailer) Send(ctx context.Context, msg mailMessage) error {
+	slog.InfoContext(ctx, "mail not sent (MAIL_DRIVER=log)", "to_email", msg.To, "subject", msg.Subject)
+	return nil
+}
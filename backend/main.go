@@ -1,97 +1,250 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"auction-system/config"
+	"auction-system/models"
 
+	"github.com/XSAM/otelsql"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var db *sql.DB
-var jwtKey = []byte("your_secret_key") // Change this in production
+
+// shutdownTimeout bounds how long in-flight requests and workers may take
+// to finish once a termination signal arrives
+const shutdownTimeout = 30 * time.Second
 
 func main() {
-	// Load environment variables
-	initDB()
+	setupLogging()
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	// Load environment variables before anything reads them
 	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: .env file not found: %v", err)
+		slog.Warn(".env file not found", "error", err)
 	}
+	initDB()
 	// Connect to database
 	if err := config.Connect(); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer config.Close()
+	registerDBMetrics(db)
 
-	// Initialize Gin router
-	r := gin.Default()
+	r, err := setupRouter()
+	if err != nil {
+		log.Fatalf("Failed to set up router: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	workers := newWorkerGroup()
+	workers.every("auction-lifecycle", lifecycleInterval, advanceAuctions)
+
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// Start server
+	slog.Info("server started", "addr", srv.Addr)
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server failed", "error", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining requests")
+	}
+
+	// Fail readiness first so no new traffic arrives, then let in-flight
+	// requests such as bids finish before stopping workers and the pools.
+	shuttingDown.Store(true)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("HTTP shutdown incomplete", "error", err)
+	}
+	if err := workers.stop(shutdownCtx); err != nil {
+		slog.Warn("background workers did not stop in time", "error", err)
+	}
+	if err := waitForMail(shutdownCtx); err != nil {
+		slog.Warn("pending mail not delivered before shutdown", "error", err)
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("closing database failed", "error", err)
+	}
+	config.Close()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("flushing traces failed", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// setupRouter builds the Gin engine with every API route registered
+func setupRouter() (*gin.Engine, error) {
+	spec, err := loadOpenAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	r := gin.New()
+	r.Use(requestID, otelgin.Middleware(serviceName), observeRequests, accessLog, gin.CustomRecovery(recoverPanic))
+	r.NoRoute(notFound)
 
 	// Use Gin's official CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", requestIDHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", requestIDHeader},
 		AllowCredentials: true,
 	}))
 
-	// API routes
+	// Liveness and readiness probes, and Prometheus metrics
+	r.GET("/healthz", healthz)
+	r.GET("/readyz", readyz)
+	r.GET("/metrics", serveMetrics())
+
+	// API routes. Protected routes authenticate before validating so
+	// anonymous callers get a 401 rather than a schema error.
+	registerValidators(config.NewAuctionRules())
+	authConfig = config.NewAuthConfig()
+	if tokenKeys, err = loadKeyring(authConfig); err != nil {
+		return nil, err
+	}
+	loginLimits = config.NewLoginThrottle()
+	accountPolicy = config.NewAccountPolicy()
+	mailConfig := config.NewMailConfig()
+	appURL = mailConfig.AppURL
+	if mailer, err = newMailer(mailConfig); err != nil {
+		return nil, err
+	}
+	validate := validateRequest(spec)
 	api := r.Group("/api")
 	{
+		api.GET("/openapi.json", serveOpenAPI(spec))
+
+		public := api.Group("/", validate)
+
 		// Auth routes
-		api.POST("/users/register", registerUser)
-		api.POST("/users/login", loginUser)
-		api.POST("/admin/login", adminLogin)
-		api.POST("/sellers/login", sellerLogin)
-		api.POST("/sellers/register", registerSeller)
+		public.POST("/users/register", registerUser)
+		public.POST("/sellers/register", registerSeller)
+		public.POST("/auth/login", login)
+		public.POST("/auth/refresh", refreshSession)
+		public.POST("/auth/logout", logout)
+		public.POST("/auth/password-reset", requestPasswordReset)
+		public.POST("/auth/password-reset/confirm", confirmPasswordReset)
+		public.POST("/auth/verify-email", verifyEmail)
+		public.POST("/auth/mfa/enroll", enrollDuringLogin)
+		public.POST("/auth/mfa/verify", verifyLoginCode)
 
 		// Protected routes
 		auth := api.Group("/")
-		auth.Use(authMiddleware)
+		auth.Use(authMiddleware, validate)
 		{
-			auth.POST("/auctions", createItem)
+			auth.POST("/auth/verify-email/resend", resendVerification)
+			auth.GET("/account", getAccount)
+			auth.POST("/account/roles", addAccountRole)
+			auth.POST("/auctions", requireRole(roleSeller), createItem)
+			auth.POST("/auctions/drafts", requireRole(roleSeller), createDraft)
+			auth.POST("/auctions/reverse", requireRole(roleBidder), createReverseAuction)
+			auth.POST("/auctions/:itemId/publish", requireRole(roleSeller), publishItem)
+			// Buyers own reverse auctions and sellers bid in them, so
+			// updateItem checks ownership and placeBid the role per format
+			auth.PUT("/auctions/:itemId", updateItem)
 			auth.POST("/auctions/:itemId/bid", placeBid)
 			auth.POST("/auctions/:itemId/cancel", cancelAuction)
+			auth.POST("/auctions/:itemId/split", splitLot)
+			auth.POST("/auctions/merge", mergeListings)
+			auth.PUT("/auctions/:itemId/absentee-bid", requireRole(roleBidder), placeAbsenteeBid)
+			auth.POST("/live-sessions", requireRole(roleAuctioneer), createLiveSession)
+			auth.POST("/live-sessions/:sessionId/lots", requireRole(roleSeller), addSessionLot)
+			// Admins may step in for the session's auctioneer
+			auth.POST("/live-sessions/:sessionId/console", sessionConsole)
 			auth.GET("/notifications", func(c *gin.Context) {
 				c.JSON(http.StatusOK, []gin.H{})
 			})
 			auth.PUT("/notifications/:id/read", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+				c.JSON(http.StatusOK, models.MessageResponse{Message: "Notification marked as read"})
 			})
 			auth.DELETE("/notifications/clear", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Notifications cleared"})
+				c.JSON(http.StatusOK, models.MessageResponse{Message: "Notifications cleared"})
 			})
 		}
 
+		// Two-factor settings for sellers and admins
+		account := api.Group("/account/mfa")
+		account.Use(authMiddleware, requireMFARole, validate)
+		{
+			account.POST("/enroll", enrollMFA)
+			account.POST("/confirm", confirmMFA)
+			account.POST("/recovery-codes", regenerateRecoveryCodes)
+			account.POST("/disable", disableMFA)
+		}
+
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(authMiddleware, requireRole(roleAdmin), validate)
+		{
+			admin.POST("/accounts/:id/revoke-sessions", revokeAccountSessions)
+			admin.POST("/accounts/:id/unlock", unlockAccount)
+			admin.POST("/accounts/:id/mfa/reset", resetMFA)
+			admin.PUT("/accounts/:id/roles/:role", grantAccountRole)
+			admin.DELETE("/accounts/:id/roles/:role", revokeAccountRole)
+			admin.GET("/audit-events", listAuditEvents)
+		}
+
 		// Public routes
-		api.GET("/auctions", listItems)
-		api.GET("/auctions/:itemId", getItem)
+		public.GET("/auctions", listItems)
+		public.GET("/auctions/upcoming", listUpcomingItems)
+		public.GET("/auctions/:itemId", getItem)
+		public.GET("/auctions/:itemId/revisions", listItemRevisions)
+		public.GET("/auctions/:itemId/stream", streamItem)
+		public.GET("/live-sessions", listLiveSessions)
+		public.GET("/live-sessions/:sessionId", getLiveSession)
+		public.GET("/live-sessions/:sessionId/stream", streamLiveSession)
 
 		// Seller routes
-		api.GET("/sellers/:id/auctions", getSellerAuctions)
+		public.GET("/sellers/:id/auctions", optionalAuth, getSellerAuctions)
 	}
 
-	// Start server
-	log.Println("Server started successfully")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+	return r, nil
 }
 
 func initDB() {
 	var err error
 	connStr := "user='postgres' password='chandana' dbname='auction_systems' host='localhost' port='5432' sslmode=disable"
 
-	db, err = sql.Open("postgres", connStr)
+	db, err = otelsql.Open("postgres", connStr, otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -103,7 +256,7 @@ func initDB() {
 
 	var dbNameCheck string
 	db.QueryRow("SELECT current_database()").Scan(&dbNameCheck)
-	log.Printf("Connected to database: %s", dbNameCheck)
+	slog.Info("connected to database", "database", dbNameCheck)
 
 	// Drop existing tables if they exist
 	// _, err = db.Exec(`
@@ -118,72 +271,48 @@ func initDB() {
 	// }
 	// log.Println("Dropped existing tables")
 
-	// Create tables
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS sellers (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(100) NOT NULL,
-			email VARCHAR(255) NOT NULL UNIQUE,
-			password_hash VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(100) NOT NULL,
-			email VARCHAR(255) NOT NULL UNIQUE,
-			password_hash VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS admins (
-			id SERIAL PRIMARY KEY,
-			username VARCHAR(100) NOT NULL UNIQUE,
-			password_hash VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS items (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(200) NOT NULL,
-			description TEXT,
-			starting_price DECIMAL(10,2) NOT NULL,
-			seller_id INTEGER REFERENCES sellers(id),
-			end_time TIMESTAMP NOT NULL,
-			status VARCHAR(20) DEFAULT 'active',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS bids (
-			id SERIAL PRIMARY KEY,
-			item_id INTEGER REFERENCES items(id),
-			bidder_id INTEGER REFERENCES users(id),
-			bid_amount DECIMAL(10,2) NOT NULL,
-			bid_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	if err != nil {
-		log.Fatal("Error creating tables:", err)
+	// Create or upgrade tables
+	if err := migrate(context.Background(), db); err != nil {
+		log.Fatal("Error migrating database:", err)
 	}
-	log.Println("Created database tables successfully")
+	slog.Info("database schema is up to date", "version", latestMigration())
 
 	// Create default admin
 	var adminCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM admins").Scan(&adminCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM account_roles WHERE role = $1", roleAdmin).Scan(&adminCount)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	if adminCount == 0 {
-		hash, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+		// ADMIN_PASSWORD sets the first admin's password; without it a
+		// random one is generated and logged once
+		password := os.Getenv("ADMIN_PASSWORD")
+		generated := password == ""
+		if generated {
+			password = randomToken(18)
+		} else if len(password) < 12 {
+			log.Fatal("ADMIN_PASSWORD must be at least 12 characters")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 		if err != nil {
 			log.Fatal(err)
 		}
-		_, err = db.Exec("INSERT INTO admins (username, password_hash) VALUES ($1, $2)", "admin", string(hash))
+		var id int
+		err = db.QueryRow("INSERT INTO accounts (name, username, password_hash) VALUES ($1, $2, $3) RETURNING id",
+			"Administrator", "admin", string(hash)).Scan(&id)
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Println("Default admin created")
+		if err := grantRole(context.Background(), db, id, roleAdmin); err != nil {
+			log.Fatal(err)
+		}
+		if generated {
+			slog.Warn("default admin created with a generated password; change it after first login",
+				"username", "admin", "generated_admin_password", password)
+		} else {
+			slog.Info("default admin created", "username", "admin")
+		}
 	}
 
 	// Create dummy data
@@ -191,52 +320,35 @@ func initDB() {
 }
 
 func createDummyData() {
-	log.Println("Starting dummy data creation...")
+	slog.Info("creating dummy data")
 
 	// Create first seller
+	ctx := context.Background()
 	hash1, _ := bcrypt.GenerateFromPassword([]byte("seller123"), bcrypt.DefaultCost)
-	var seller1ID int
-	err := db.QueryRow(`
-		INSERT INTO sellers (name, email, password_hash) 
-		VALUES ($1, $2, $3) 
-		RETURNING id`,
-		"Demo Seller", "seller@example.com", string(hash1),
-	).Scan(&seller1ID)
+	seller1ID, err := createAccount(ctx, "Demo Seller", "seller@example.com", string(hash1), roleSeller)
 	if err != nil {
-		log.Printf("Error creating first seller: %v", err)
+		slog.Warn("creating first seller failed", "error", err)
 		return
 	}
-	log.Printf("Created first seller with ID: %d", seller1ID)
+	slog.Info("created seller", "account_id", seller1ID)
 
 	// Create second seller
 	hash2, _ := bcrypt.GenerateFromPassword([]byte("seller456"), bcrypt.DefaultCost)
-	var seller2ID int
-	err = db.QueryRow(`
-		INSERT INTO sellers (name, email, password_hash) 
-		VALUES ($1, $2, $3) 
-		RETURNING id`,
-		"Luxury Auctions", "luxury@example.com", string(hash2),
-	).Scan(&seller2ID)
+	seller2ID, err := createAccount(ctx, "Luxury Auctions", "luxury@example.com", string(hash2), roleSeller)
 	if err != nil {
-		log.Printf("Error creating second seller: %v", err)
+		slog.Warn("creating second seller failed", "error", err)
 		return
 	}
-	log.Printf("Created second seller with ID: %d", seller2ID)
+	slog.Info("created seller", "account_id", seller2ID)
 
 	// Create dummy user
 	userHash, _ := bcrypt.GenerateFromPassword([]byte("user123"), bcrypt.DefaultCost)
-	var userID int
-	err = db.QueryRow(`
-		INSERT INTO users (name, email, password_hash) 
-		VALUES ($1, $2, $3) 
-		RETURNING id`,
-		"Demo User", "user@example.com", string(userHash),
-	).Scan(&userID)
+	userID, err := createAccount(ctx, "Demo User", "user@example.com", string(userHash), roleBidder)
 	if err != nil {
-		log.Printf("Error creating dummy user: %v", err)
+		slog.Warn("creating dummy user failed", "error", err)
 		return
 	}
-	log.Printf("Created dummy user with ID: %d", userID)
+	slog.Info("created bidder", "account_id", userID)
 
 	// Create dummy auctions
 	dummyItems := []struct {
@@ -245,6 +357,7 @@ func createDummyData() {
 		startingPrice float64
 		sellerId      int
 		endTime       string
+		lot           []string
 	}{
 		{
 			name:          "Vintage Rolex Submariner",
@@ -273,6 +386,13 @@ func createDummyData() {
 			startingPrice: 45000.00,
 			sellerId:      seller2ID,
 			endTime:       "NOW() + INTERVAL '3 days'",
+			lot: []string{
+				"Château Lafite Rothschild 1982", "Château Lafite Rothschild 1982",
+				"Château Lafite Rothschild 1983", "Château Lafite Rothschild 1984",
+				"Château Lafite Rothschild 1985", "Château Lafite Rothschild 1986",
+				"Château Lafite Rothschild 1987", "Château Lafite Rothschild 1988",
+				"Château Lafite Rothschild 1989", "Château Lafite Rothschild 1990",
+			},
 		},
 	}
 
@@ -286,10 +406,21 @@ func createDummyData() {
 			item.name, item.description, item.startingPrice, item.sellerId,
 		).Scan(&itemID)
 		if err != nil {
-			log.Printf("Error creating auction %s: %v", item.name, err)
+			slog.Warn("creating auction failed", "name", item.name, "error", err)
 			continue
 		}
-		log.Printf("Created auction: %s (ID: %d)", item.name, itemID)
+		slog.Info("created auction", "item_id", itemID, "name", item.name)
+
+		for i, name := range item.lot {
+			_, err := db.Exec(`
+				INSERT INTO lot_items (lot_id, position, name, description)
+				VALUES ($1, $2, $3, 'One 750ml bottle, stored in a temperature-controlled cellar.')`,
+				itemID, i+1, name,
+			)
+			if err != nil {
+				slog.Warn("creating lot item failed", "item_id", itemID, "error", err)
+			}
+		}
 
 		// Add some bids
 		currentPrice := item.startingPrice
@@ -303,507 +434,524 @@ func createDummyData() {
 				itemID, userID, currentPrice, i+1,
 			)
 			if err != nil {
-				log.Printf("Error creating bid for auction %d: %v", itemID, err)
+				slog.Warn("creating bid failed", "item_id", itemID, "error", err)
 			} else {
-				log.Printf("Added bid of %.2f for auction %d", currentPrice, itemID)
+				slog.Info("created bid", "item_id", itemID, "amount", currentPrice)
 			}
 		}
 	}
 
-	log.Println("Dummy data creation completed")
+	slog.Info("dummy data created")
 }
 
 func registerUser(c *gin.Context) {
 	var req struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Name     string `json:"name" binding:"required,notblank,max=100"`
+		Email    string `json:"email" binding:"required,email,max=255"`
+		Password string `json:"password" binding:"required,password"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	if !bindJSON(c, &req) {
 		return
 	}
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Server error"})
+		respondError(c, http.StatusInternalServerError, codeInternal, "Server error")
+		return
+	}
+	id, err := createAccount(c.Request.Context(), req.Name, req.Email, string(hash), roleBidder)
+	if isUniqueViolation(err) {
+		respondError(c, http.StatusConflict, codeConflict, "Email already registered",
+			models.FieldError{Field: "email", Message: "already registered"})
 		return
 	}
-	_, err = db.Exec(
-		"INSERT INTO users (name, email, password_hash) VALUES ($1, $2, $3)",
-		req.Name, req.Email, string(hash),
-	)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already registered"})
+		respondDBError(c, err, "Could not register user")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "User registered successfully"})
+	if err := sendVerificationEmail(c.Request.Context(), id, req.Name, req.Email); err != nil {
+		slog.ErrorContext(c.Request.Context(), "issuing verification email failed", "error", err)
+	}
+	auditRegistration(c, id, req.Name, req.Email, roleBidder)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "User registered successfully"})
 }
 
-func loginUser(c *gin.Context) {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+func authMiddleware(c *gin.Context) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" || len(authHeader) < 8 || authHeader[:7] != "Bearer " {
+		abortError(c, http.StatusUnauthorized, codeUnauthorized, "Missing or invalid token")
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	tokenStr := authHeader[7:]
+	claims, err := tokenKeys.parse(tokenStr)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		abortError(c, http.StatusUnauthorized, codeTokenExpired, "Token expired")
 		return
 	}
-	var id int
-	var name, hash string
-	err := db.QueryRow("SELECT id, name, password_hash FROM users WHERE email = $1", req.Email).
-		Scan(&id, &name, &hash)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		abortError(c, http.StatusUnauthorized, codeInvalidToken, "Invalid token")
 		return
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+
+	// Access tokens are bound to a session so logout and revocation take
+	// effect before the token expires
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		abortError(c, http.StatusUnauthorized, codeInvalidToken, "Invalid token")
 		return
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": id,
-		"name":    name,
-		"email":   req.Email,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
-	tokenString, err := token.SignedString(jwtKey)
+	id, roles, active, err := sessionAccount(c.Request.Context(), sessionID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		slog.ErrorContext(c.Request.Context(), "session lookup failed", "error", err)
+		abortError(c, http.StatusInternalServerError, codeInternal, "Could not verify session")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
-}
-
-func authMiddleware(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" || len(authHeader) < 8 || authHeader[:7] != "Bearer " {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
+	if !active {
+		abortError(c, http.StatusUnauthorized, codeSessionRevoked, "Session has been revoked")
 		return
 	}
-	tokenStr := authHeader[7:]
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-	if err != nil || !token.Valid {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	c.Set("session_id", sessionID)
+	c.Set("account_id", id)
+	c.Set("roles", roles)
+	c.Next()
+}
+
+// optionalAuth authenticates the caller when a token is sent, so public
+// routes can show more to the account concerned; without one the request
+// continues anonymously
+func optionalAuth(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		c.Next()
 		return
 	}
-	claims := token.Claims.(jwt.MapClaims)
-	// Support both user_id and seller_id
-	if userID, ok := claims["user_id"].(float64); ok {
-		c.Set("user_id", int(userID))
-	}
-	if sellerID, ok := claims["seller_id"].(float64); ok {
-		c.Set("seller_id", int(sellerID))
-	}
-	c.Next()
+	authMiddleware(c)
 }
 
 func createItem(c *gin.Context) {
 	var req struct {
-		Name          string  `json:"name"`
-		Description   string  `json:"description"`
-		StartingPrice float64 `json:"starting_price"`
-		EndTime       string  `json:"end_time"`
+		Name          string           `json:"name" binding:"required,notblank,max=200"`
+		Description   string           `json:"description" binding:"max=5000"`
+		StartingPrice float64          `json:"starting_price" binding:"required,finite,gt=0,lte=99999999.99"`
+		StartTime     *time.Time       `json:"start_time" binding:"omitempty,auction_start"`
+		EndTime       time.Time        `json:"end_time" binding:"required,auction_end"`
+		Format        string           `json:"format" binding:"omitempty,oneof=english dutch sealed multi_unit"`
+		Dutch         *dutchTerms      `json:"dutch" binding:"required_if=Format dutch,excluded_unless=Format dutch"`
+		Sealed        *sealedTerms     `json:"sealed" binding:"required_if=Format sealed,excluded_unless=Format sealed"`
+		MultiUnit     *multiUnitTerms  `json:"multi_unit" binding:"required_if=Format multi_unit,excluded_unless=Format multi_unit"`
+		LotItems      []lotItemRequest `json:"lot_items" binding:"excluded_if=Format multi_unit,omitempty,min=2,max=100,dive"`
+		Relist        *relistTerms     `json:"relist"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if details := dutchProblems(req.StartingPrice, req.Dutch); len(details) > 0 {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input", details...)
+		return
+	}
+	sellerID := accountID(c)
+	// Auctions with a start time are announced as upcoming until then
+	status := "active"
+	created := map[string]any{
+		"name":           req.Name,
+		"description":    req.Description,
+		"starting_price": req.StartingPrice,
+		"end_time":       req.EndTime,
+		"format":         cmp.Or(req.Format, formatEnglish),
+	}
+	if req.Dutch != nil {
+		created["dutch"] = req.Dutch
+	}
+	if req.Sealed != nil {
+		created["sealed"] = req.Sealed
+	}
+	if req.MultiUnit != nil {
+		created["multi_unit"] = req.MultiUnit
+	}
+	if len(req.LotItems) > 0 {
+		created["lot_size"] = len(req.LotItems)
+	}
+	if req.Relist != nil {
+		created["relist"] = req.Relist
+	}
+	if req.StartTime != nil {
+		status = "upcoming"
+		created["start_time"] = *req.StartTime
+	}
+	step, interval, floor := req.Dutch.columns()
+	quantity, clearing := req.MultiUnit.columns()
+	relistMax, relistDrop := req.Relist.columns()
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not create item")
+		return
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	defer tx.Rollback()
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO items (name, description, starting_price, seller_id, start_time, end_time, status,
+		                   format, price_step, price_interval_seconds, floor_price, sealed_pricing, quantity, clearing,
+		                   relist_max, relist_price_drop)
+		VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id
+	`, req.Name, req.Description, req.StartingPrice, sellerID, req.StartTime, req.EndTime, status,
+		cmp.Or(req.Format, formatEnglish), step, interval, floor, req.Sealed.column(), quantity, clearing,
+		relistMax, relistDrop).Scan(&id)
+	if err != nil {
+		respondDBError(c, err, "Could not create item")
 		return
 	}
-	sellerID := c.GetInt("seller_id")
-	if sellerID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: seller_id missing"})
+	if err := insertLotItems(ctx, tx, id, req.LotItems); err != nil {
+		respondDBError(c, err, "Could not create item")
 		return
 	}
-	_, err := db.Exec(
-		"INSERT INTO items (name, description, starting_price, seller_id, end_time) VALUES ($1, $2, $3, $4, $5)",
-		req.Name, req.Description, req.StartingPrice, sellerID, req.EndTime,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create item"})
+	audit(c, tx, auditEvent{action: auditItemCreated, targetType: auditTargetItem, targetID: id,
+		diff: auditDiff(nil, created)})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not create item")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Item created"})
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Item created"})
 }
 
-func listItems(c *gin.Context) {
-	rows, err := db.Query(`
-		SELECT i.id, i.name, i.description, i.starting_price, COALESCE(MAX(b.bid_amount), i.starting_price) as current_price, u.name, i.end_time
+// itemStatusSQL derives the public status of an item aliased as i
+const itemStatusSQL = `CASE
+		WHEN i.status = 'draft' THEN 'draft'
+		WHEN i.status = 'cancelled' THEN 'cancelled'
+		WHEN i.status IN ('sold', 'unsold') OR i.end_time < NOW() THEN 'ended'
+		WHEN i.session_id IS NOT NULL THEN CASE WHEN i.status = 'active' THEN 'active' ELSE 'upcoming' END
+		WHEN i.start_time > NOW() THEN 'upcoming'
+		ELSE 'active'
+	END`
+
+// itemSummarySQL selects the columns read by scanItem from items i, its
+// seller u and its bids b, grouped by i.id and u.name
+const itemSummarySQL = `i.id, i.name, COALESCE(i.description, ''), i.starting_price,
+		CASE
+			WHEN i.format = 'sealed' THEN ` + sealedPriceSQL + `
+			WHEN i.format = 'dutch' AND i.start_time IS NOT NULL AND COUNT(b.id) = 0 THEN ` + dutchPriceSQL + `
+			WHEN i.format = 'reverse' THEN COALESCE(MIN(b.bid_amount), i.starting_price)
+			WHEN i.format = 'multi_unit' THEN ` + clearingPriceSQL + `
+			ELSE COALESCE(MAX(b.bid_amount), i.starting_price)
+		END,
+		i.seller_id, u.name, ` + itemStatusSQL + `, i.format, COUNT(b.id),
+		i.start_time, i.end_time, i.cancelled_at, COALESCE(i.cancellation_reason, ''),
+		i.price_step, i.price_interval_seconds, i.floor_price, ` + dutchNextDropSQL + `, i.sealed_pricing, i.bid_decrement,
+		i.quantity, i.clearing, COALESCE(SUM(b.quantity), 0),
+		(SELECT COUNT(*) FROM lot_items l WHERE l.lot_id = i.id), i.split_from, i.merged_into,
+		i.session_id, i.session_position, COALESCE(i.live_state, ''),
+		i.relist_max, i.relist_price_drop, i.relist_count, i.relisted_from,
+		(SELECT r.id FROM items r WHERE r.relisted_from = i.id)`
+
+// scanItem reads a row selected with itemSummarySQL
+func scanItem(row interface{ Scan(...any) error }, item *models.ItemSummary) error {
+	var step, floor sql.NullFloat64
+	var interval sql.NullInt64
+	var nextDrop sql.NullTime
+	var pricing sql.NullString
+	var decrement sql.NullFloat64
+	var quantity, unitsBid int
+	var clearing sql.NullString
+	var sessionID, position sql.NullInt64
+	var call string
+	var relist models.RelistRule
+	err := row.Scan(&item.ID, &item.Name, &item.Description, &item.StartingPrice, &item.CurrentPrice,
+		&item.SellerID, &item.Seller, &item.Status, &item.Format, &item.BidCount, &item.StartTime, &item.EndTime,
+		&item.CancelledAt, &item.CancellationReason, &step, &interval, &floor, &nextDrop, &pricing, &decrement,
+		&quantity, &clearing, &unitsBid, &item.LotSize, &item.SplitFrom, &item.MergedInto,
+		&sessionID, &position, &call, &relist.MaxRelists, &relist.PriceDropPercent, &relist.Relists,
+		&item.RelistedFrom, &item.RelistedAs)
+	if err != nil {
+		return err
+	}
+	if item.Format == formatDutch {
+		item.Dutch = &models.DutchPrice{
+			PriceStep:       step.Float64,
+			IntervalSeconds: int(interval.Int64),
+			FloorPrice:      floor.Float64,
+		}
+		if nextDrop.Valid {
+			item.Dutch.NextDropAt = &nextDrop.Time
+		}
+	}
+	if item.Format == formatSealed {
+		item.Sealed = &models.SealedBids{Pricing: pricing.String}
+	}
+	if item.Format == formatReverse {
+		item.Reverse = &models.ReverseBids{BidDecrement: decrement.Float64}
+	}
+	if item.Format == formatMultiUnit {
+		item.MultiUnit = &models.MultiUnit{Quantity: quantity, Clearing: clearing.String, UnitsBid: unitsBid}
+	}
+	if sessionID.Valid {
+		item.Live = &models.LiveLot{SessionID: int(sessionID.Int64), Position: int(position.Int64), Call: call}
+	}
+	if relist.MaxRelists > 0 {
+		item.Relist = &relist
+	}
+	return nil
+}
+
+// queryItems lists the items matching a condition on i, in the given order
+func queryItems(ctx context.Context, where, orderBy string, args ...any) ([]models.ItemSummary, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+itemSummarySQL+`
 		FROM items i
-		JOIN users u ON i.seller_id = u.id
+		JOIN accounts u ON i.seller_id = u.id
 		LEFT JOIN bids b ON b.item_id = i.id
-		WHERE i.end_time > NOW()
+		WHERE `+where+`
 		GROUP BY i.id, u.name
-		ORDER BY i.end_time ASC
-	`)
+		ORDER BY `+orderBy, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch items"})
-		return
+		return nil, err
 	}
 	defer rows.Close()
-	var items []gin.H
+	items := []models.ItemSummary{}
 	for rows.Next() {
-		var id int
-		var name, description, seller string
-		var startingPrice, currentPrice float64
-		var endTime time.Time
-		rows.Scan(&id, &name, &description, &startingPrice, &currentPrice, &seller, &endTime)
-		items = append(items, gin.H{
-			"id": id, "name": name, "description": description,
-			"starting_price": startingPrice, "current_price": currentPrice,
-			"seller": seller, "end_time": endTime,
-		})
+		var item models.ItemSummary
+		if err := scanItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// activeItemsSQL matches auctions open for bidding: started, not yet ended
+// and neither cancelled nor settled early, as a bought Dutch auction is. A
+// live-session lot has no end time and is open while it is on the block.
+// An upcoming auction whose start has passed takes bids before the
+// lifecycle worker marks it active, so it counts as well.
+const activeItemsSQL = `COALESCE(i.status, 'active') IN ('active', 'upcoming') AND i.start_time <= NOW()
+	AND (i.end_time > NOW() OR (i.session_id IS NOT NULL AND i.status = 'active'))`
+
+// listItems returns the auctions open for bidding, ending soonest first;
+// open live-session lots, which have no end time, come last
+func listItems(c *gin.Context) {
+	items, err := queryItems(c.Request.Context(), activeItemsSQL, "i.end_time ASC NULLS LAST")
+	if err != nil {
+		respondDBError(c, err, "Could not fetch items")
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// listUpcomingItems returns announced auctions that have not started yet,
+// starting soonest first
+func listUpcomingItems(c *gin.Context) {
+	items, err := queryItems(c.Request.Context(),
+		"i.start_time > NOW() AND COALESCE(i.status, 'active') <> 'cancelled'", "i.start_time ASC")
+	if err != nil {
+		respondDBError(c, err, "Could not fetch items")
+		return
 	}
 	c.JSON(http.StatusOK, items)
 }
 
 func getItem(c *gin.Context) {
-	itemId := c.Param("itemId")
-	var item struct {
-		ID                          int
-		Name, Description, Seller   string
-		StartingPrice, CurrentPrice float64
-		EndTime                     time.Time
-	}
-	err := db.QueryRow(`
-		SELECT i.id, i.name, i.description, u.name, i.starting_price, COALESCE(MAX(b.bid_amount), i.starting_price), i.end_time
+	detail, err := loadItemDetail(c.Request.Context(), c.Param("itemId"))
+	// Drafts are only shown to their seller, on the seller dashboard
+	if err == sql.ErrNoRows || (err == nil && detail.Item.Status == "draft") {
+		respondError(c, http.StatusNotFound, codeNotFound, "Item not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, detail)
+}
+
+// loadItem reads the listing representation of one item
+func loadItem(ctx context.Context, itemID string) (models.ItemSummary, error) {
+	var item models.ItemSummary
+	row := db.QueryRowContext(ctx, `
+		SELECT `+itemSummarySQL+`
 		FROM items i
-		JOIN users u ON i.seller_id = u.id
+		JOIN accounts u ON i.seller_id = u.id
 		LEFT JOIN bids b ON b.item_id = i.id
 		WHERE i.id = $1
 		GROUP BY i.id, u.name
-	`, itemId).Scan(&item.ID, &item.Name, &item.Description, &item.Seller, &item.StartingPrice, &item.CurrentPrice, &item.EndTime)
+	`, itemID)
+	return item, scanItem(row, &item)
+}
+
+// itemBids returns the bids on an item, newest first
+func itemBids(ctx context.Context, itemID int) ([]models.BidView, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT b.bidder_id, u.name, b.bid_amount, b.bid_time, b.quantity, b.units_won, b.unit_price
+		FROM bids b
+		JOIN accounts u ON b.bidder_id = u.id
+		WHERE b.item_id = $1
+		ORDER BY b.bid_time DESC
+	`, itemID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-		return
+		return nil, err
 	}
-	rows, _ := db.Query("SELECT bidder_id, bid_amount, bid_time FROM bids WHERE item_id = $1 ORDER BY bid_time DESC", itemId)
-	var bids []gin.H
+	defer rows.Close()
+	bids := []models.BidView{}
 	for rows.Next() {
-		var bidderID int
-		var amount float64
-		var bidTime time.Time
-		rows.Scan(&bidderID, &amount, &bidTime)
-		bids = append(bids, gin.H{"bidder_id": bidderID, "amount": amount, "bid_time": bidTime})
-	}
-	c.JSON(http.StatusOK, gin.H{
-		"item": item,
-		"bids": bids,
-	})
+		var bid models.BidView
+		if err := rows.Scan(&bid.BidderID, &bid.BidderName, &bid.Amount, &bid.BidTime,
+			&bid.Quantity, &bid.UnitsWon, &bid.UnitPrice); err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
 }
 
 func placeBid(c *gin.Context) {
 	itemId := c.Param("itemId")
-	userID := c.GetInt("user_id")
+	userID := accountID(c)
 	var req struct {
-		BidAmount float64 `json:"bid_amount"`
+		BidAmount float64 `json:"bid_amount" binding:"required,finite,gt=0,lte=99999999.99"`
+		Quantity  int     `json:"quantity" binding:"omitempty,min=1,max=10000"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	if !bindJSON(c, &req) {
+		bidRejected(bidRejectedInvalid)
 		return
 	}
-	var sellerID int
-	var endTime time.Time
-	err := db.QueryRow("SELECT seller_id, end_time FROM items WHERE id = $1", itemId).Scan(&sellerID, &endTime)
+	units := max(req.Quantity, 1)
+	target, rej, err := validateBid(c.Request.Context(), itemId, userID, accountRoles(c), req.BidAmount, units)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-		return
-	}
-	if sellerID == userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot bid on your own item"})
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
 		return
 	}
-	if endTime.Before(time.Now()) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Auction has ended"})
+	if rej != nil {
+		bidRejected(rej.reason)
+		respondError(c, rej.status, rej.code, rej.message, rej.details...)
 		return
 	}
-	var currentPrice float64
-	db.QueryRow("SELECT COALESCE(MAX(bid_amount), (SELECT starting_price FROM items WHERE id = $1)) FROM bids WHERE item_id = $1", itemId).Scan(&currentPrice)
-	if req.BidAmount <= currentPrice {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bid must be higher than current price"})
+	switch {
+	case target.live:
+		placeLiveBid(c, itemId, userID, req.BidAmount)
 		return
-	}
-	_, err = db.Exec("INSERT INTO bids (item_id, bidder_id, bid_amount) VALUES ($1, $2, $3)", itemId, userID, req.BidAmount)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not place bid"})
+	case target.format == formatDutch:
+		acceptDutchPrice(c, itemId, userID)
 		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "Bid placed"})
-}
-
-func adminLogin(c *gin.Context) {
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	case target.format == formatSealed:
+		placeSealedBid(c, itemId, userID, req.BidAmount)
 		return
-	}
-
-	var id int
-	var hash string
-	err := db.QueryRow("SELECT id, password_hash FROM admins WHERE username = $1", req.Username).
-		Scan(&id, &hash)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	case target.format == formatMultiUnit:
+		placeUnitsBid(c, itemId, userID, req.BidAmount, units)
 		return
-	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	case target.format == formatReverse:
+		placeReverseBid(c, itemId, userID, req.BidAmount)
 		return
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"admin_id": id,
-		"username": req.Username,
-		"role":     "admin",
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
-	})
-
-	tokenString, err := token.SignedString(jwtKey)
+	_, err = db.ExecContext(c.Request.Context(), "INSERT INTO bids (item_id, bidder_id, bid_amount, quantity) VALUES ($1, $2, $3, $4)", itemId, userID, req.BidAmount, units)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"admin": gin.H{
-			"id":       id,
-			"username": req.Username,
-		},
-	})
+	bidAccepted()
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Bid placed"})
 }
 
 func registerSeller(c *gin.Context) {
 	var req struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-		return
+		Name     string `json:"name" binding:"required,notblank,max=100"`
+		Email    string `json:"email" binding:"required,email,max=255"`
+		Password string `json:"password" binding:"required,password"`
 	}
-
-	// Check if email already exists
-	var existingID int
-	err := db.QueryRow("SELECT id FROM sellers WHERE email = $1", req.Email).Scan(&existingID)
-	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already registered"})
-		return
-	} else if err != sql.ErrNoRows {
-		log.Printf("Database error checking email: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Create password hash
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Error creating password hash: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Server error"})
+		slog.ErrorContext(c.Request.Context(), "hashing password failed", "error", err)
+		respondError(c, http.StatusInternalServerError, codeInternal, "Server error")
 		return
 	}
 
-	// Insert new seller
-	var id int
-	err = db.QueryRow(
-		"INSERT INTO sellers (name, email, password_hash) VALUES ($1, $2, $3) RETURNING id",
-		req.Name, req.Email, string(hash),
-	).Scan(&id)
-
-	if err != nil {
-		log.Printf("Error creating seller: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create seller"})
+	// Sellers can bid on other auctions with the same account
+	id, err := createAccount(c.Request.Context(), req.Name, req.Email, string(hash), roleBidder, roleSeller)
+	if isUniqueViolation(err) {
+		respondError(c, http.StatusConflict, codeConflict, "Email already registered",
+			models.FieldError{Field: "email", Message: "already registered"})
 		return
 	}
-
-	// Create JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"seller_id": id,
-		"name":      req.Name,
-		"email":     req.Email,
-		"role":      "seller",
-		"exp":       time.Now().Add(24 * time.Hour).Unix(),
-	})
-
-	tokenString, err := token.SignedString(jwtKey)
 	if err != nil {
-		log.Printf("Error creating token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		respondDBError(c, err, "Could not create seller")
 		return
 	}
 
-	log.Printf("Successfully registered seller: %s (ID: %d)", req.Email, id)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Seller registered successfully",
-		"token":   tokenString,
-		"seller": gin.H{
-			"id":    id,
-			"name":  req.Name,
-			"email": req.Email,
-		},
-	})
-}
-
-func sellerLogin(c *gin.Context) {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("Login request error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-		return
+	if err := sendVerificationEmail(c.Request.Context(), id, req.Name, req.Email); err != nil {
+		slog.ErrorContext(c.Request.Context(), "issuing verification email failed", "error", err)
 	}
+	auditRegistration(c, id, req.Name, req.Email, roleBidder, roleSeller)
 
-	log.Printf("Login attempt for email: %s", req.Email)
-
-	var id int
-	var name, hash string
-	err := db.QueryRow("SELECT id, name, password_hash FROM sellers WHERE email = $1", req.Email).
-		Scan(&id, &name, &hash)
+	// Log the new seller straight in
+	account, err := loadAccount(c.Request.Context(), db, id)
 	if err != nil {
-		log.Printf("Database error: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondDBError(c, err, "Could not create session")
 		return
 	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)); err != nil {
-		log.Printf("Password mismatch for email: %s", req.Email)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	tokens, err := startSession(c, account)
+	if err != nil {
+		respondDBError(c, err, "Could not create session")
 		return
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"seller_id": id,
-		"name":      name,
-		"email":     req.Email,
-		"role":      "seller",
-		"exp":       time.Now().Add(24 * time.Hour).Unix(),
+	slog.InfoContext(c.Request.Context(), "seller registered", "account_id", id)
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message:       "Seller registered successfully",
+		TokenResponse: tokens,
+		Account:       account,
 	})
+}
 
-	tokenString, err := token.SignedString(jwtKey)
+func getSellerAuctions(c *gin.Context) {
+	sellerID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		log.Printf("Error creating token: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		respondError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid seller ID")
 		return
 	}
 
-	log.Printf("Successful login for seller: %s (ID: %d)", req.Email, id)
-	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"seller": gin.H{
-			"id":    id,
-			"name":  name,
-			"email": req.Email,
-		},
-	})
-}
-
-func getSellerAuctions(c *gin.Context) {
-	sellerID := c.Param("id")
-
-	// Get all auctions for this seller, including cancelled ones
-	rows, err := db.Query(`
-		SELECT i.id, i.name, i.description, i.starting_price, 
-		       CASE 
-		           WHEN i.status = 'cancelled' THEN 'cancelled'
-		           WHEN i.end_time < NOW() THEN 'ended'
-		           ELSE 'active'
-		       END as status,
-		       i.end_time,
-		       COALESCE(MAX(b.bid_amount), i.starting_price) as current_bid,
-		       s.name as seller_name
+	// Get all auctions for this seller, including cancelled ones, and
+	// drafts when the seller is asking
+	rows, err := db.QueryContext(c.Request.Context(), `
+		SELECT `+itemSummarySQL+`
 		FROM items i
-		JOIN sellers s ON i.seller_id = s.id
+		JOIN accounts u ON i.seller_id = u.id
 		LEFT JOIN bids b ON b.item_id = i.id
-		WHERE i.seller_id = $1
-		GROUP BY i.id, s.name, i.status
-		ORDER BY i.end_time DESC
-	`, sellerID)
+		WHERE i.seller_id = $1 AND (i.status IS DISTINCT FROM 'draft' OR $2)
+		GROUP BY i.id, u.name
+		ORDER BY i.end_time DESC NULLS FIRST
+	`, sellerID, sellerID == accountID(c))
 
 	if err != nil {
-		log.Printf("Error fetching seller auctions: %v", err)
-		c.JSON(http.StatusOK, []gin.H{}) // Return empty array on error
+		respondDBError(c, err, "Could not fetch seller auctions")
 		return
 	}
 	defer rows.Close()
 
-	var auctions []gin.H = []gin.H{} // Ensure initialized as empty array
+	auctions := []models.SellerAuction{} // Ensure initialized as empty array
 	for rows.Next() {
-		var id int
-		var name, description, status, sellerName string
-		var startingPrice, currentBid float64
-		var endTime time.Time
-
-		err := rows.Scan(&id, &name, &description, &startingPrice, &status, &endTime, &currentBid, &sellerName)
-		if err != nil {
-			log.Printf("Error scanning auction row: %v", err)
-			continue
+		var auction models.SellerAuction
+		if err := scanItem(rows, &auction.ItemSummary); err != nil {
+			respondDBError(c, err, "Could not fetch seller auctions")
+			return
 		}
 
 		// Get bids for this auction
-		bidRows, err := db.Query(`
-			SELECT b.bid_amount, b.bid_time, u.name as bidder_name
-			FROM bids b
-			JOIN users u ON b.bidder_id = u.id
-			WHERE b.item_id = $1
-			ORDER BY b.bid_time DESC
-		`, id)
-
+		auction.Bids, err = revealedBids(c.Request.Context(), auction.ItemSummary)
 		if err != nil {
-			log.Printf("Error fetching bids for auction %d: %v", id, err)
-			continue
-		}
-
-		var bids []gin.H
-		for bidRows.Next() {
-			var amount float64
-			var bidTime time.Time
-			var bidderName string
-
-			err := bidRows.Scan(&amount, &bidTime, &bidderName)
-			if err != nil {
-				log.Printf("Error scanning bid row: %v", err)
-				continue
-			}
-
-			bids = append(bids, gin.H{
-				"amount":    amount,
-				"timestamp": bidTime,
-				"userName":  bidderName,
-			})
+			respondDBError(c, err, "Could not fetch seller auctions")
+			return
 		}
-		bidRows.Close()
 
-		auctions = append(auctions, gin.H{
-			"id":          id,
-			"title":       name,
-			"description": description,
-			"basePrice":   startingPrice,
-			"currentBid":  currentBid,
-			"status":      status,
-			"endTime":     endTime,
-			"sellerName":  sellerName,
-			"bids":        bids,
-		})
+		auctions = append(auctions, auction)
+	}
+	if err := rows.Err(); err != nil {
+		respondDBError(c, err, "Could not fetch seller auctions")
+		return
 	}
 
 	// Always return an array, even if empty
 	c.JSON(http.StatusOK, auctions)
 }
-
-func cancelAuction(c *gin.Context) {
-	auctionID := c.Param("itemId")
-	_, err := db.Exec("UPDATE items SET status = 'cancelled' WHERE id = $1", auctionID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not cancel auction"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "Auction cancelled successfully"})
-}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"auction-system/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRegistry holds every metric exposed on /metrics. A dedicated
+// registry keeps tests free of global registration conflicts.
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auction_http_requests_total",
+		Help: "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auction_http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	bidsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auction_bids_total",
+		Help: "Bids by result (accepted, rejected) and rejection reason.",
+	}, []string{"result", "reason"})
+
+	activeAuctions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "auction_active_auctions",
+		Help: "Auctions currently open for bidding.",
+	})
+
+	auctionsOpenedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auction_auctions_opened_total",
+		Help: "Scheduled auctions opened for bidding by the lifecycle worker.",
+	})
+
+	auctionsClosedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auction_auctions_closed_total",
+		Help: "Auctions closed by the lifecycle worker, by outcome (sold, unsold).",
+	}, []string{"outcome"})
+
+	loginFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auction_login_failures_total",
+		Help: "Failed login attempts by the factor that failed (password, mfa).",
+	}, []string{"factor"})
+
+	loginThrottledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auction_login_throttled_total",
+		Help: "Login attempts rejected by brute-force protection, by scope (account, ip).",
+	}, []string{"scope"})
+
+	streamSubscribers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "auction_stream_subscribers",
+		Help: "Clients connected to live streams, by stream (item, session).",
+	}, []string{"stream"})
+)
+
+// Bid rejection reasons used as the "reason" label of auction_bids_total
+const (
+	bidRejectedInvalid    = "invalid"
+	bidRejectedNotFound   = "not_found"
+	bidRejectedOwnItem    = "own_item"
+	bidRejectedRole       = "role"
+	bidRejectedClosed     = "auction_closed"
+	bidRejectedNotStarted = "not_started"
+	bidRejectedTooLow     = "too_low"
+	bidRejectedTooHigh    = "too_high"
+	bidRejectedDuplicate  = "already_bid"
+	bidRejectedUnverified = "email_unverified"
+	bidRejectedError      = "error"
+)
+
+// Login factors used as the "factor" label of auction_login_failures_total
+const (
+	factorPassword = "password"
+	factorMFA      = "mfa"
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		bidsTotal,
+		activeAuctions,
+		auctionsOpenedTotal,
+		auctionsClosedTotal,
+		loginFailuresTotal,
+		loginThrottledTotal,
+		streamSubscribers,
+	)
+}
+
+// registerDBMetrics exposes connection pool statistics for both pools
+func registerDBMetrics(sqlDB *sql.DB) {
+	metricsRegistry.MustRegister(collectors.NewDBStatsCollector(sqlDB, "auction"))
+	if config.DB == nil {
+		return
+	}
+	pool := config.DB
+	metricsRegistry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "auction_pgxpool_total_conns",
+			Help: "Connections currently in the pgx pool.",
+		}, func() float64 { return float64(pool.Stat().TotalConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "auction_pgxpool_acquired_conns",
+			Help: "Connections currently checked out of the pgx pool.",
+		}, func() float64 { return float64(pool.Stat().AcquiredConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "auction_pgxpool_idle_conns",
+			Help: "Idle connections in the pgx pool.",
+		}, func() float64 { return float64(pool.Stat().IdleConns()) }),
+	)
+}
+
+// observeRequests records the count and latency of every request, labelled
+// by route pattern rather than raw path to keep cardinality bounded
+func observeRequests(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+}
+
+// serveMetrics exposes the registry in the Prometheus text format
+func serveMetrics() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+}
+
+func bidAccepted() {
+	bidsTotal.WithLabelValues("accepted", "").Inc()
+}
+
+func bidRejected(reason string) {
+	bidsTotal.WithLabelValues("rejected", reason).Inc()
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// mfaChallengeTTL is how long a password-verified login waits for its code
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts caps the codes tried against one challenge
+	mfaMaxAttempts = 5
+	// recoveryCodeCount is how many recovery codes each enrollment gets
+	recoveryCodeCount = 10
+)
+
+// mfaRoles are the roles whose holders can enroll; admins must
+var mfaRoles = []string{roleSeller, roleAdmin}
+
+// mfaAllowed reports whether any of the roles may use two-factor
+// authentication
+func mfaAllowed(roles []string) bool {
+	return slices.ContainsFunc(mfaRoles, func(r string) bool { return slices.Contains(roles, r) })
+}
+
+// mfaThrottleKey counts wrong codes per account, on top of the per-
+// challenge limit, so requesting fresh challenges does not reset guessing
+func mfaThrottleKey(id int) throttleKey {
+	return throttleKey{scopeAccount, fmt.Sprintf("#%d:mfa", id)}
+}
+
+// requireSecondFactor is called by login once the password has been
+// checked. If the account needs a TOTP code it writes a challenge instead
+// of tokens and returns true.
+func requireSecondFactor(c *gin.Context, id int) bool {
+	ctx := c.Request.Context()
+	account, err := loadAccount(ctx, db, id)
+	if err != nil {
+		respondDBError(c, err, "Could not log in")
+		return true
+	}
+	if !mfaAllowed(account.Roles) {
+		return false
+	}
+	enrolled, err := mfaEnrolled(ctx, id)
+	if err != nil {
+		respondDBError(c, err, "Could not log in")
+		return true
+	}
+	if !enrolled && !slices.Contains(account.Roles, roleAdmin) {
+		return false
+	}
+
+	token := randomToken(32)
+	_, err = db.ExecContext(ctx,
+		"INSERT INTO mfa_challenges (token_hash, account_id, expires_at) VALUES ($1, $2, $3)",
+		hashToken(token), id, time.Now().Add(mfaChallengeTTL))
+	if err != nil {
+		respondDBError(c, err, "Could not log in")
+		return true
+	}
+	c.JSON(http.StatusOK, models.MFAChallengeResponse{
+		MFARequired:        true,
+		MFAToken:           token,
+		ExpiresIn:          int(mfaChallengeTTL.Seconds()),
+		EnrollmentRequired: !enrolled,
+	})
+	return true
+}
+
+// mfaEnrolled reports whether the account has a confirmed TOTP factor
+func mfaEnrolled(ctx context.Context, id int) (bool, error) {
+	var enrolled bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM mfa_factors WHERE account_id = $1 AND confirmed_at IS NOT NULL)",
+		id).Scan(&enrolled)
+	return enrolled, err
+}
+
+// startEnrollment stores a new unconfirmed TOTP secret for the account,
+// replacing any earlier unconfirmed one
+func startEnrollment(ctx context.Context, id int) (models.MFAEnrollmentResponse, error) {
+	account, err := loadAccount(ctx, db, id)
+	if err != nil {
+		return models.MFAEnrollmentResponse{}, err
+	}
+	label := account.Email
+	if label == "" {
+		label = account.Username
+	}
+
+	secret := newTOTPSecret()
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO mfa_factors (account_id, secret) VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE SET secret = $2, last_used_step = 0, created_at = NOW()
+		WHERE mfa_factors.confirmed_at IS NULL
+	`, id, secret)
+	if err != nil {
+		return models.MFAEnrollmentResponse{}, err
+	}
+	return models.MFAEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: otpauthURI(authConfig.MFAIssuer, label, secret),
+	}, nil
+}
+
+// checkSecondFactor verifies a TOTP code, or a recovery code when the
+// factor is confirmed, inside tx. Used TOTP steps and recovery codes are
+// burned. confirming means the factor must still be unconfirmed and only a
+// TOTP code is accepted; it is then confirmed.
+func checkSecondFactor(ctx context.Context, tx *sql.Tx, id int, code string, confirming bool) (bool, error) {
+	var secret string
+	var confirmedAt sql.NullTime
+	var lastStep int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT secret, confirmed_at, last_used_step FROM mfa_factors
+		WHERE account_id = $1 FOR UPDATE
+	`, id).Scan(&secret, &confirmedAt, &lastStep)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if confirmedAt.Valid == confirming {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := verifyTOTP(secret, code, time.Now(), lastStep); ok {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE mfa_factors SET last_used_step = $2, confirmed_at = COALESCE(confirmed_at, NOW())
+			WHERE account_id = $1
+		`, id, step)
+		return err == nil, err
+	}
+	if confirming {
+		return false, nil
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE id = (SELECT id FROM mfa_recovery_codes
+			WHERE account_id = $1 AND code_hash = $2 AND used_at IS NULL LIMIT 1)
+	`, id, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if n == 1 {
+		slog.InfoContext(ctx, "recovery code used", "account_id", id)
+	}
+	return n == 1, nil
+}
+
+// newRecoveryCodes replaces the account's recovery codes and returns the
+// new ones in plain text
+func newRecoveryCodes(ctx context.Context, tx *sql.Tx, id int) ([]string, error) {
+	_, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE account_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		rand.Read(b)
+		raw := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO mfa_recovery_codes (account_id, code_hash) VALUES ($1, $2)",
+			id, hashToken(raw))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts codes typed with any case or separators
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// completeLogin starts a session for an account that passed every factor
+// and writes its tokens and profile
+func completeLogin(c *gin.Context, id int, recoveryCodes []string) {
+	ctx := c.Request.Context()
+	account, err := loadAccount(ctx, db, id)
+	if err != nil {
+		respondDBError(c, err, "Could not create session")
+		return
+	}
+	tokens, err := startSession(c, account)
+	if err != nil {
+		respondDBError(c, err, "Could not create session")
+		return
+	}
+	slog.InfoContext(ctx, "logged in", "account_id", id)
+	audit(c, db, auditEvent{action: auditLogin, actor: id, targetType: auditTargetAccount, targetID: id})
+	c.JSON(http.StatusOK, models.AuthResponse{
+		TokenResponse: tokens,
+		Account:       account,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// loadChallenge locks a login challenge and counts an attempt against it
+func loadChallenge(ctx context.Context, tx *sql.Tx, token string) (id int, err error) {
+	var attempts int
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT account_id, attempts, expires_at FROM mfa_challenges
+		WHERE token_hash = $1 FOR UPDATE
+	`, hashToken(token)).Scan(&id, &attempts, &expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	if attempts >= mfaMaxAttempts || time.Now().After(expiresAt) {
+		return 0, sql.ErrNoRows
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = $1", hashToken(token))
+	return id, err
+}
+
+// enrollDuringLogin gives an admin without a factor a TOTP secret to set up
+// before their first login can complete
+func enrollDuringLogin(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required,max=200"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var id int
+	err := db.QueryRowContext(ctx,
+		"SELECT account_id FROM mfa_challenges WHERE token_hash = $1 AND expires_at > NOW()",
+		hashToken(req.MFAToken)).Scan(&id)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusUnauthorized, codeInvalidToken, "Invalid or expired MFA token")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not start enrollment")
+		return
+	}
+	enrolled, err := mfaEnrolled(ctx, id)
+	if err != nil {
+		respondDBError(c, err, "Could not start enrollment")
+		return
+	}
+	if enrolled {
+		respondError(c, http.StatusConflict, codeConflict, "Two-factor authentication is already set up")
+		return
+	}
+	enrollment, err := startEnrollment(ctx, id)
+	if err != nil {
+		respondDBError(c, err, "Could not start enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// verifyLoginCode finishes a login with a TOTP or recovery code. For an
+// admin enrolling at first login, the code confirms the new factor and the
+// response includes their recovery codes.
+func verifyLoginCode(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required,max=200"`
+		Code     string `json:"code" binding:"required,max=32"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return
+	}
+	defer tx.Rollback()
+
+	id, err := loadChallenge(ctx, tx, req.MFAToken)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusUnauthorized, codeInvalidToken, "Invalid or expired MFA token")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return
+	}
+	throttle := mfaThrottleKey(id)
+	reserved, ok := reserveAttempt(c, throttle)
+	if !ok {
+		return
+	}
+
+	enrolled, err := mfaEnrolled(ctx, id)
+	if err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return
+	}
+	ok, err = checkSecondFactor(ctx, tx, id, req.Code, !enrolled)
+	if err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return
+	}
+	if !ok {
+		// Keep the attempt count even though the login failed
+		if err := tx.Commit(); err != nil {
+			respondDBError(c, err, "Could not verify code")
+			return
+		}
+		for _, r := range reserved {
+			r.failed(ctx)
+		}
+		loginFailuresTotal.WithLabelValues(factorMFA).Inc()
+		audit(c, db, auditEvent{action: auditLoginFailed, targetType: auditTargetAccount, targetID: id,
+			details: map[string]string{"factor": factorMFA}})
+		respondError(c, http.StatusUnauthorized, codeInvalidMFACode, "Invalid authentication code")
+		return
+	}
+
+	var recoveryCodes []string
+	if !enrolled {
+		if recoveryCodes, err = newRecoveryCodes(ctx, tx, id); err != nil {
+			respondDBError(c, err, "Could not verify code")
+			return
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_challenges WHERE token_hash = $1", hashToken(req.MFAToken)); err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return
+	}
+	clearLoginFailures(ctx, throttle)
+	if !enrolled {
+		slog.InfoContext(ctx, "two-factor enrolled", "account_id", id)
+		audit(c, db, auditEvent{action: auditMFAEnabled, actor: id, targetType: auditTargetAccount, targetID: id})
+	}
+	completeLogin(c, id, recoveryCodes)
+}
+
+// requireMFARole limits the account 2FA endpoints to sellers and admins
+func requireMFARole(c *gin.Context) {
+	if !slices.ContainsFunc(mfaRoles, func(r string) bool { return hasRole(c, r) }) {
+		abortError(c, http.StatusForbidden, codeForbidden, "Two-factor authentication is available to sellers and admins")
+		return
+	}
+	c.Next()
+}
+
+// enrollMFA starts TOTP enrollment for the signed-in account
+func enrollMFA(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := accountID(c)
+	enrolled, err := mfaEnrolled(ctx, id)
+	if err != nil {
+		respondDBError(c, err, "Could not start enrollment")
+		return
+	}
+	if enrolled {
+		respondError(c, http.StatusConflict, codeConflict, "Two-factor authentication is already set up")
+		return
+	}
+	enrollment, err := startEnrollment(ctx, id)
+	if err != nil {
+		respondDBError(c, err, "Could not start enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// mfaCodeRequest carries a TOTP or recovery code
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// withSecondFactor runs fn in a transaction once the caller's code checks
+// out, applying the same wrong-code throttling as login. It reports whether
+// fn's changes were committed; otherwise an error has been written.
+func withSecondFactor(c *gin.Context, confirming bool, fn func(ctx context.Context, tx *sql.Tx, id int) error) bool {
+	var req mfaCodeRequest
+	if !bindJSON(c, &req) {
+		return false
+	}
+	ctx := c.Request.Context()
+	id := accountID(c)
+	throttle := mfaThrottleKey(id)
+	reserved, ok := reserveAttempt(c, throttle)
+	if !ok {
+		return false
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return false
+	}
+	defer tx.Rollback()
+
+	ok, err = checkSecondFactor(ctx, tx, id, req.Code, confirming)
+	if err != nil {
+		respondDBError(c, err, "Could not verify code")
+		return false
+	}
+	if !ok {
+		for _, r := range reserved {
+			r.failed(ctx)
+		}
+		respondError(c, http.StatusUnauthorized, codeInvalidMFACode, "Invalid authentication code")
+		return false
+	}
+	if err := fn(ctx, tx, id); err != nil {
+		respondDBError(c, err, "Could not update two-factor authentication")
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not update two-factor authentication")
+		return false
+	}
+	clearLoginFailures(ctx, throttle)
+	return true
+}
+
+// confirmMFA finishes enrollment with a first code and returns recovery codes
+func confirmMFA(c *gin.Context) {
+	var codes []string
+	ok := withSecondFactor(c, true, func(ctx context.Context, tx *sql.Tx, id int) (err error) {
+		codes, err = newRecoveryCodes(ctx, tx, id)
+		return err
+	})
+	if ok {
+		slog.InfoContext(c.Request.Context(), "two-factor enrolled", "account_id", accountID(c))
+		audit(c, db, auditEvent{action: auditMFAEnabled, targetType: auditTargetAccount, targetID: accountID(c)})
+		c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// regenerateRecoveryCodes replaces the caller's recovery codes
+func regenerateRecoveryCodes(c *gin.Context) {
+	var codes []string
+	ok := withSecondFactor(c, false, func(ctx context.Context, tx *sql.Tx, id int) (err error) {
+		codes, err = newRecoveryCodes(ctx, tx, id)
+		return err
+	})
+	if ok {
+		audit(c, db, auditEvent{action: auditRecoveryCodes, targetType: auditTargetAccount, targetID: accountID(c)})
+		c.JSON(http.StatusOK, models.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// disableMFA removes a seller's second factor. Admins cannot opt out.
+func disableMFA(c *gin.Context) {
+	if hasRole(c, roleAdmin) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Two-factor authentication is mandatory for admins")
+		return
+	}
+	ok := withSecondFactor(c, false, func(ctx context.Context, tx *sql.Tx, id int) error {
+		return deleteSecondFactor(ctx, tx, id)
+	})
+	if ok {
+		slog.InfoContext(c.Request.Context(), "two-factor disabled", "account_id", accountID(c))
+		audit(c, db, auditEvent{action: auditMFADisabled, targetType: auditTargetAccount, targetID: accountID(c)})
+		c.JSON(http.StatusOK, models.MessageResponse{Message: "Two-factor authentication disabled"})
+	}
+}
+
+// deleteSecondFactor removes an account's factor, recovery codes and
+// pending challenges
+func deleteSecondFactor(ctx context.Context, ex execer, id int) error {
+	for _, table := range []string{"mfa_factors", "mfa_recovery_codes", "mfa_challenges"} {
+		_, err := ex.ExecContext(ctx, "DELETE FROM "+table+" WHERE account_id = $1", id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetMFA lets an admin remove another account's second factor, e.g.
+// after a lost phone. Admins are asked to enroll again at their next login.
+func resetMFA(c *gin.Context) {
+	id, ok := adminAccountID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	if err := deleteSecondFactor(ctx, db, id); err != nil {
+		respondDBError(c, err, "Could not reset two-factor authentication")
+		return
+	}
+	clearLoginFailures(ctx, mfaThrottleKey(id))
+	slog.InfoContext(ctx, "two-factor reset by admin",
+		"admin_id", accountID(c), "account_id", id)
+	audit(c, db, auditEvent{action: auditMFAReset, targetType: auditTargetAccount, targetID: id})
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Two-factor authentication reset"})
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migration is one forward-only schema change. Versions must be
+// consecutive; never edit a migration once it has shipped, add a new one.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		sql: `
+		CREATE TABLE IF NOT EXISTS sellers (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			email VARCHAR(255) NOT NULL UNIQUE,
+			password_hash VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS users (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			email VARCHAR(255) NOT NULL UNIQUE,
+			password_hash VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS admins (
+			id SERIAL PRIMARY KEY,
+			username VARCHAR(100) NOT NULL UNIQUE,
+			password_hash VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS items (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			description TEXT,
+			starting_price DECIMAL(10,2) NOT NULL,
+			seller_id INTEGER REFERENCES sellers(id),
+			end_time TIMESTAMP NOT NULL,
+			status VARCHAR(20) DEFAULT 'active',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS bids (
+			id SERIAL PRIMARY KEY,
+			item_id INTEGER REFERENCES items(id),
+			bidder_id INTEGER REFERENCES users(id),
+			bid_amount DECIMAL(10,2) NOT NULL,
+			bid_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+	},
+	{
+		version: 2,
+		name:    "auction closing",
+		sql: `
+		ALTER TABLE items ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS items_status_end_time_idx ON items (status, end_time);`,
+	},
+	{
+		version: 3,
+		name:    "sessions and refresh tokens",
+		sql: `
+		CREATE TABLE sessions (
+			id VARCHAR(64) PRIMARY KEY,
+			account_role VARCHAR(10) NOT NULL,
+			account_id INTEGER NOT NULL,
+			user_agent TEXT,
+			ip VARCHAR(64),
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revoked_at TIMESTAMP,
+			revoked_reason VARCHAR(50)
+		);
+		CREATE INDEX sessions_account_idx ON sessions (account_role, account_id);
+
+		CREATE TABLE refresh_tokens (
+			token_hash CHAR(64) PRIMARY KEY,
+			session_id VARCHAR(64) NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX refresh_tokens_session_idx ON refresh_tokens (session_id);`,
+	},
+	{
+		version: 4,
+		name:    "login throttling",
+		sql: `
+		CREATE TABLE login_throttle (
+			scope VARCHAR(10) NOT NULL,
+			key VARCHAR(320) NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP NOT NULL,
+			locked_until TIMESTAMP,
+			PRIMARY KEY (scope, key)
+		);`,
+	},
+	{
+		version: 5,
+		name:    "password reset and email verification",
+		sql: `
+		ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+		ALTER TABLE sellers ADD COLUMN email_verified_at TIMESTAMP;
+
+		CREATE TABLE account_tokens (
+			token_hash CHAR(64) PRIMARY KEY,
+			purpose VARCHAR(20) NOT NULL,
+			account_role VARCHAR(10) NOT NULL,
+			account_id INTEGER NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX account_tokens_account_idx ON account_tokens (account_role, account_id, purpose);`,
+	},
+	{
+		version: 6,
+		name:    "two-factor authentication",
+		sql: `
+		CREATE TABLE mfa_factors (
+			account_role VARCHAR(10) NOT NULL,
+			account_id INTEGER NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			confirmed_at TIMESTAMP,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (account_role, account_id)
+		);
+
+		CREATE TABLE mfa_recovery_codes (
+			id SERIAL PRIMARY KEY,
+			account_role VARCHAR(10) NOT NULL,
+			account_id INTEGER NOT NULL,
+			code_hash CHAR(64) NOT NULL,
+			used_at TIMESTAMP
+		);
+		CREATE INDEX mfa_recovery_codes_account_idx ON mfa_recovery_codes (account_role, account_id);
+
+		CREATE TABLE mfa_challenges (
+			token_hash CHAR(64) PRIMARY KEY,
+			account_role VARCHAR(10) NOT NULL,
+			account_id INTEGER NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL
+		);`,
+	},
+	{
+		version: 7,
+		name:    "unified accounts",
+		sql: `
+		CREATE TABLE accounts (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			email VARCHAR(255),
+			username VARCHAR(100),
+			password_hash VARCHAR(255) NOT NULL,
+			email_verified_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			CHECK (email IS NOT NULL OR username IS NOT NULL)
+		);
+		CREATE UNIQUE INDEX accounts_email_idx ON accounts (lower(email));
+		CREATE UNIQUE INDEX accounts_username_idx ON accounts (lower(username));
+
+		CREATE TABLE account_roles (
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			role VARCHAR(10) NOT NULL CHECK (role IN ('bidder', 'seller', 'admin')),
+			granted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (account_id, role)
+		);
+
+		-- A user and a seller with the same email are one person and become
+		-- one account. The newest row supplies the name and password.
+		INSERT INTO accounts (name, email, password_hash, email_verified_at, created_at)
+		SELECT DISTINCT ON (lower(email)) name, email, password_hash,
+		       MAX(email_verified_at) OVER w, COALESCE(MIN(created_at) OVER w, CURRENT_TIMESTAMP)
+		FROM (
+			SELECT name, email, password_hash, email_verified_at, created_at FROM users
+			UNION ALL
+			SELECT name, email, password_hash, email_verified_at, created_at FROM sellers
+		) people
+		WINDOW w AS (PARTITION BY lower(email))
+		ORDER BY lower(email), created_at DESC NULLS LAST;
+
+		INSERT INTO accounts (name, username, password_hash, created_at)
+		SELECT username, username, password_hash, COALESCE(created_at, CURRENT_TIMESTAMP) FROM admins;
+
+		-- old_role is the role the old tables and sessions used
+		CREATE TEMP TABLE account_map (
+			old_role VARCHAR(10) NOT NULL,
+			old_id INTEGER NOT NULL,
+			account_id INTEGER NOT NULL,
+			PRIMARY KEY (old_role, old_id)
+		) ON COMMIT DROP;
+		INSERT INTO account_map
+		SELECT 'user', u.id, a.id FROM users u JOIN accounts a ON lower(a.email) = lower(u.email);
+		INSERT INTO account_map
+		SELECT 'seller', s.id, a.id FROM sellers s JOIN accounts a ON lower(a.email) = lower(s.email);
+		INSERT INTO account_map
+		SELECT 'admin', ad.id, a.id FROM admins ad JOIN accounts a ON a.username = ad.username;
+
+		INSERT INTO account_roles (account_id, role)
+		SELECT DISTINCT account_id, CASE old_role WHEN 'user' THEN 'bidder' ELSE old_role END
+		FROM account_map;
+
+		-- Auctions and bids point at the merged accounts
+		ALTER TABLE items DROP CONSTRAINT IF EXISTS items_seller_id_fkey;
+		UPDATE items i SET seller_id = m.account_id
+		FROM account_map m WHERE m.old_role = 'seller' AND m.old_id = i.seller_id;
+		ALTER TABLE items ADD CONSTRAINT items_seller_id_fkey FOREIGN KEY (seller_id) REFERENCES accounts(id);
+
+		ALTER TABLE bids DROP CONSTRAINT IF EXISTS bids_bidder_id_fkey;
+		UPDATE bids b SET bidder_id = m.account_id
+		FROM account_map m WHERE m.old_role = 'user' AND m.old_id = b.bidder_id;
+		ALTER TABLE bids ADD CONSTRAINT bids_bidder_id_fkey FOREIGN KEY (bidder_id) REFERENCES accounts(id);
+
+		-- Sessions, tokens and second factors keep working under the new IDs
+		DELETE FROM sessions s WHERE NOT EXISTS (
+			SELECT 1 FROM account_map m WHERE m.old_role = s.account_role AND m.old_id = s.account_id);
+		UPDATE sessions s SET account_id = m.account_id
+		FROM account_map m WHERE m.old_role = s.account_role AND m.old_id = s.account_id;
+		DROP INDEX sessions_account_idx;
+		ALTER TABLE sessions DROP COLUMN account_role;
+		ALTER TABLE sessions ADD FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+		CREATE INDEX sessions_account_idx ON sessions (account_id);
+
+		DELETE FROM account_tokens t WHERE NOT EXISTS (
+			SELECT 1 FROM account_map m WHERE m.old_role = t.account_role AND m.old_id = t.account_id);
+		UPDATE account_tokens t SET account_id = m.account_id
+		FROM account_map m WHERE m.old_role = t.account_role AND m.old_id = t.account_id;
+		DROP INDEX account_tokens_account_idx;
+		ALTER TABLE account_tokens DROP COLUMN account_role;
+		ALTER TABLE account_tokens ADD FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+		CREATE INDEX account_tokens_account_idx ON account_tokens (account_id, purpose);
+
+		DELETE FROM mfa_factors f WHERE NOT EXISTS (
+			SELECT 1 FROM account_map m WHERE m.old_role = f.account_role AND m.old_id = f.account_id);
+		UPDATE mfa_factors f SET account_id = m.account_id
+		FROM account_map m WHERE m.old_role = f.account_role AND m.old_id = f.account_id;
+		ALTER TABLE mfa_factors DROP CONSTRAINT mfa_factors_pkey;
+		ALTER TABLE mfa_factors DROP COLUMN account_role;
+		ALTER TABLE mfa_factors ADD PRIMARY KEY (account_id);
+		ALTER TABLE mfa_factors ADD FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+
+		DELETE FROM mfa_recovery_codes r WHERE NOT EXISTS (
+			SELECT 1 FROM account_map m WHERE m.old_role = r.account_role AND m.old_id = r.account_id);
+		UPDATE mfa_recovery_codes r SET account_id = m.account_id
+		FROM account_map m WHERE m.old_role = r.account_role AND m.old_id = r.account_id;
+		DROP INDEX mfa_recovery_codes_account_idx;
+		ALTER TABLE mfa_recovery_codes DROP COLUMN account_role;
+		ALTER TABLE mfa_recovery_codes ADD FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+		CREATE INDEX mfa_recovery_codes_account_idx ON mfa_recovery_codes (account_id);
+
+		-- Pending second-factor logins and per-role lockouts are short-lived;
+		-- start them afresh rather than translate them
+		DELETE FROM mfa_challenges;
+		ALTER TABLE mfa_challenges DROP COLUMN account_role;
+		ALTER TABLE mfa_challenges ADD FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE;
+		DELETE FROM login_throttle WHERE scope = 'account';
+
+		DROP TABLE users, sellers, admins;`,
+	},
+	{
+		version: 8,
+		name:    "audit events",
+		sql: `
+		CREATE TABLE audit_events (
+			id BIGSERIAL PRIMARY KEY,
+			occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			action VARCHAR(50) NOT NULL,
+			actor_id INTEGER,
+			target_type VARCHAR(30) NOT NULL,
+			target_id VARCHAR(64),
+			ip VARCHAR(64),
+			user_agent TEXT,
+			request_id VARCHAR(64),
+			diff JSONB,
+			details JSONB
+		);
+		CREATE INDEX audit_events_action_idx ON audit_events (action, id);
+		CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, id);
+		CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, id);
+		CREATE INDEX audit_events_occurred_at_idx ON audit_events (occurred_at);
+
+		-- The log is append-only, even for the application's own role
+		CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_events is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER audit_events_no_update BEFORE UPDATE OR DELETE ON audit_events
+			FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+		CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+			FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();`,
+	},
+	{
+		version: 9,
+		name:    "auction cancellation",
+		sql: `
+		ALTER TABLE items ADD COLUMN cancelled_at TIMESTAMP;
+		ALTER TABLE items ADD COLUMN cancelled_by INTEGER REFERENCES accounts(id) ON DELETE SET NULL;
+		ALTER TABLE items ADD COLUMN cancellation_reason TEXT;`,
+	},
+	{
+		version: 10,
+		name:    "item revisions",
+		sql: `
+		CREATE TABLE item_revisions (
+			item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+			revision INTEGER NOT NULL,
+			edited_by INTEGER REFERENCES accounts(id) ON DELETE SET NULL,
+			edited_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			changes JSONB NOT NULL,
+			PRIMARY KEY (item_id, revision)
+		);`,
+	},
+	{
+		version: 11,
+		name:    "scheduled start times",
+		sql: `
+		ALTER TABLE items ADD COLUMN start_time TIMESTAMP;
+		UPDATE items SET start_time = LEAST(COALESCE(created_at, CURRENT_TIMESTAMP), end_time);
+		ALTER TABLE items ALTER COLUMN start_time SET DEFAULT CURRENT_TIMESTAMP;
+		ALTER TABLE items ALTER COLUMN start_time SET NOT NULL;
+		ALTER TABLE items ADD CONSTRAINT items_start_before_end CHECK (start_time < end_time) NOT VALID;
+		CREATE INDEX items_status_start_time_idx ON items (status, start_time);`,
+	},
+	{
+		version: 12,
+		name:    "draft listings",
+		sql: `
+		ALTER TABLE items ALTER COLUMN start_time DROP NOT NULL;
+		ALTER TABLE items ALTER COLUMN end_time DROP NOT NULL;
+		ALTER TABLE items ADD CONSTRAINT items_timing_required
+			CHECK (status = 'draft' OR (start_time IS NOT NULL AND end_time IS NOT NULL));`,
+	},
+	{
+		version: 13,
+		name:    "dutch auctions",
+		sql: `
+		ALTER TABLE items ADD COLUMN format VARCHAR(20) NOT NULL DEFAULT 'english';
+		ALTER TABLE items ADD CONSTRAINT items_format_check CHECK (format IN ('english', 'dutch'));
+		ALTER TABLE items ADD COLUMN price_step DECIMAL(10,2);
+		ALTER TABLE items ADD COLUMN price_interval_seconds INTEGER;
+		ALTER TABLE items ADD COLUMN floor_price DECIMAL(10,2);
+		ALTER TABLE items ADD CONSTRAINT items_dutch_terms CHECK (format <> 'dutch' OR (
+			price_step > 0 AND price_interval_seconds > 0 AND floor_price > 0 AND floor_price <= starting_price));`,
+	},
+	{
+		version: 14,
+		name:    "sealed-bid auctions",
+		sql: `
+		ALTER TABLE items DROP CONSTRAINT items_format_check;
+		ALTER TABLE items ADD CONSTRAINT items_format_check CHECK (format IN ('english', 'dutch', 'sealed'));
+		ALTER TABLE items ADD COLUMN sealed_pricing VARCHAR(20);
+		ALTER TABLE items ADD CONSTRAINT items_sealed_terms CHECK (
+			(format = 'sealed') = (sealed_pricing IS NOT NULL) AND sealed_pricing IN ('first_price', 'second_price'));`,
+	},
+	{
+		version: 15,
+		name:    "reverse auctions",
+		sql: `
+		ALTER TABLE items DROP CONSTRAINT items_format_check;
+		ALTER TABLE items ADD CONSTRAINT items_format_check CHECK (format IN ('english', 'dutch', 'sealed', 'reverse'));
+		ALTER TABLE items ADD COLUMN bid_decrement DECIMAL(10,2);
+		ALTER TABLE items ADD CONSTRAINT items_reverse_terms CHECK (
+			(format = 'reverse') = (bid_decrement IS NOT NULL) AND bid_decrement > 0);`,
+	},
+	{
+		version: 16,
+		name:    "multi-unit auctions",
+		sql: `
+		ALTER TABLE items DROP CONSTRAINT items_format_check;
+		ALTER TABLE items ADD CONSTRAINT items_format_check CHECK (format IN ('english', 'dutch', 'sealed', 'reverse', 'multi_unit'));
+		ALTER TABLE items ADD COLUMN quantity INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE items ADD COLUMN clearing VARCHAR(20);
+		ALTER TABLE items ADD CONSTRAINT items_multi_unit_terms CHECK (
+			(format = 'multi_unit') = (clearing IS NOT NULL) AND clearing IN ('uniform', 'pay_as_bid')
+			AND quantity >= 1 AND (format = 'multi_unit' OR quantity = 1));
+		ALTER TABLE bids ADD COLUMN quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity >= 1);
+		ALTER TABLE bids ADD COLUMN units_won INTEGER;
+		ALTER TABLE bids ADD COLUMN unit_price DECIMAL(10,2);`,
+	},
+	{
+		version: 17,
+		name:    "lots",
+		sql: `
+		CREATE TABLE lot_items (
+			id SERIAL PRIMARY KEY,
+			lot_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			name VARCHAR(200) NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			image_urls TEXT[] NOT NULL DEFAULT '{}',
+			UNIQUE (lot_id, position)
+		);
+		ALTER TABLE items ADD COLUMN split_from INTEGER REFERENCES items(id);
+		ALTER TABLE items ADD COLUMN merged_into INTEGER REFERENCES items(id);
+		CREATE INDEX items_split_from_idx ON items (split_from);`,
+	},
+	{
+		version: 18,
+		name:    "live sessions",
+		sql: `
+		ALTER TABLE account_roles DROP CONSTRAINT account_roles_role_check;
+		ALTER TABLE account_roles ADD CONSTRAINT account_roles_role_check
+			CHECK (role IN ('bidder', 'seller', 'admin', 'auctioneer'));
+		CREATE TABLE live_sessions (
+			id SERIAL PRIMARY KEY,
+			title VARCHAR(200) NOT NULL,
+			auctioneer_id INTEGER NOT NULL REFERENCES accounts(id),
+			starts_at TIMESTAMP NOT NULL,
+			bid_increment DECIMAL(10,2) NOT NULL CHECK (bid_increment > 0),
+			status VARCHAR(10) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'running', 'closed')),
+			current_item_id INTEGER REFERENCES items(id),
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE items ADD COLUMN session_id INTEGER REFERENCES live_sessions(id);
+		ALTER TABLE items ADD COLUMN session_position INTEGER;
+		ALTER TABLE items ADD COLUMN live_state VARCHAR(20)
+			CHECK (live_state IN ('open', 'accepted', 'going_once', 'going_twice'));
+		ALTER TABLE items ADD CONSTRAINT items_session_position_key UNIQUE (session_id, session_position);
+		-- Session lots have no end time until they are hammered down
+		ALTER TABLE items DROP CONSTRAINT items_timing_required;
+		ALTER TABLE items ADD CONSTRAINT items_timing_required
+			CHECK (status = 'draft' OR (start_time IS NOT NULL AND (end_time IS NOT NULL OR session_id IS NOT NULL)));
+		CREATE TABLE absentee_bids (
+			item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+			bidder_id INTEGER NOT NULL REFERENCES accounts(id),
+			max_amount DECIMAL(10,2) NOT NULL CHECK (max_amount > 0),
+			placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (item_id, bidder_id)
+		);`,
+	},
+	{
+		version: 19,
+		name:    "automatic relisting",
+		sql: `
+		ALTER TABLE items ADD COLUMN relist_max INTEGER NOT NULL DEFAULT 0 CHECK (relist_max BETWEEN 0 AND 10);
+		ALTER TABLE items ADD COLUMN relist_price_drop DECIMAL(5,2) NOT NULL DEFAULT 0
+			CHECK (relist_price_drop >= 0 AND relist_price_drop < 100);
+		ALTER TABLE items ADD COLUMN relist_count INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE items ADD COLUMN relisted_from INTEGER REFERENCES items(id);
+		-- An auction is relisted at most once; the copy carries the chain on
+		CREATE UNIQUE INDEX items_relisted_from_key ON items (relisted_from);`,
+	},
+}
+
+// migrationLockID is the advisory lock key that serialises migrations when
+// several instances start at once
+const migrationLockID = 72_640_001
+
+// latestMigration is the schema version this binary expects
+func latestMigration() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate applies every pending migration, each in its own transaction
+func migrate(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	var current int
+	err = conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		slog.Info("applied migration", "version", m.version, "name", m.name)
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// schemaVersion returns the highest applied migration, or 0 for a fresh database
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// AuditChange is one field's value before and after an audited change.
+// Before is null for created records.
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditEvent is one entry of the audit log. ActorID is null for anonymous
+// requests such as failed logins.
+type AuditEvent struct {
+	ID         int64                  `json:"id"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Action     string                 `json:"action"`
+	ActorID    *int                   `json:"actor_id"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id,omitempty"`
+	IP         string                 `json:"ip,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Diff       map[string]AuditChange `json:"diff,omitempty"`
+	Details    map[string]string      `json:"details,omitempty"`
+}
+
+// AuditEventPage is one page of audit events, newest first. NextCursor is
+// passed as cursor to fetch the following page and is absent on the last.
+type AuditEventPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor int64        `json:"next_cursor,omitempty"`
+}
@@ -9,3 +9,16 @@ type Bid struct {
 	BidAmount float64   `json:"bid_amount"`
 	BidTime   time.Time `json:"bid_time"`
 }
+
+// BidView is a bid as returned by the API
+type BidView struct {
+	BidderID   int       `json:"bidder_id"`
+	BidderName string    `json:"bidder_name"`
+	Amount     float64   `json:"amount"`
+	BidTime    time.Time `json:"bid_time"`
+	// Quantity is the number of units bid for at Amount each; UnitsWon
+	// and UnitPrice are set once a multi-unit auction closes
+	Quantity  int      `json:"quantity"`
+	UnitsWon  *int     `json:"units_won,omitempty"`
+	UnitPrice *float64 `json:"unit_price,omitempty"`
+}
@@ -10,3 +10,135 @@ type Item struct {
 	EndTime       time.Time `json:"end_time"`
 	SellerID      int       `json:"seller_id"`
 }
+
+// ItemSummary is the listing representation shared by every auction endpoint
+type ItemSummary struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	StartingPrice float64 `json:"starting_price"`
+	CurrentPrice  float64 `json:"current_price"`
+	SellerID      int     `json:"seller_id"`
+	Seller        string  `json:"seller"`
+	Status        string  `json:"status"`
+	// Format is "english" (ascending bids), "dutch" (falling price),
+	// "sealed" (hidden bids revealed at the end), "reverse" (sellers bid
+	// down from a buyer's ceiling) or "multi_unit" (several identical
+	// units, each bid naming a quantity)
+	Format string `json:"format"`
+	// BidCount is public even while sealed bids are hidden
+	BidCount int `json:"bid_count"`
+	// StartTime and EndTime are null for drafts, whose timing is set when
+	// they are published
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+	// CancelledAt and CancellationReason are set once the auction is cancelled
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason string     `json:"cancellation_reason,omitempty"`
+	// Dutch is set for Dutch auctions, whose CurrentPrice is the price
+	// on offer right now
+	Dutch *DutchPrice `json:"dutch,omitempty"`
+	// Sealed is set for sealed-bid auctions, whose CurrentPrice stays at
+	// the starting price until they end and is then what the winner pays
+	Sealed *SealedBids `json:"sealed,omitempty"`
+	// Reverse is set for reverse auctions, where the seller fields name the
+	// buyer, StartingPrice is the ceiling and CurrentPrice the lowest bid
+	Reverse *ReverseBids `json:"reverse,omitempty"`
+	// MultiUnit is set for multi-unit auctions, whose CurrentPrice is the
+	// unit price a new bid must beat
+	MultiUnit *MultiUnit `json:"multi_unit,omitempty"`
+	// LotSize is the number of catalogued items in a lot
+	LotSize int `json:"lot_size,omitempty"`
+	// SplitFrom is the lot a listing was split out of, MergedInto the lot
+	// a listing was merged into
+	SplitFrom  *int `json:"split_from,omitempty"`
+	MergedInto *int `json:"merged_into,omitempty"`
+	// Live is set for lots of a live session, which have no end time until
+	// they are hammered down
+	Live *LiveLot `json:"live,omitempty"`
+	// Relist is set for listings that are listed again when they end unsold
+	Relist *RelistRule `json:"relist,omitempty"`
+	// RelistedFrom is the unsold auction this one relists, RelistedAs the
+	// auction that relisted this one
+	RelistedFrom *int `json:"relisted_from,omitempty"`
+	RelistedAs   *int `json:"relisted_as,omitempty"`
+}
+
+// RelistRule says how an unsold auction is listed again
+type RelistRule struct {
+	MaxRelists       int     `json:"max_relists"`
+	PriceDropPercent float64 `json:"price_drop_percent"`
+	// Relists is how many times the original listing has been relisted
+	// up to this one
+	Relists int `json:"relists"`
+}
+
+// LiveLot places an item in a live session
+type LiveLot struct {
+	SessionID int `json:"session_id"`
+	Position  int `json:"position"`
+	// Call is the auctioneer's last call on an open lot: "open",
+	// "accepted", "going_once" or "going_twice"
+	Call string `json:"call,omitempty"`
+}
+
+// LotItem is one catalogued item of a lot
+type LotItem struct {
+	Position    int      `json:"position"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ImageURLs   []string `json:"image_urls"`
+}
+
+// MultiUnit describes a multi-unit auction
+type MultiUnit struct {
+	Quantity int `json:"quantity"`
+	// Clearing is "uniform" (every winner pays the lowest winning price) or
+	// "pay_as_bid" (each winner pays their own)
+	Clearing string `json:"clearing"`
+	// UnitsBid is the total number of units asked for across all bids
+	UnitsBid int `json:"units_bid"`
+}
+
+// ReverseBids describes how bids in a reverse auction must fall
+type ReverseBids struct {
+	// BidDecrement is how far each bid must undercut the lowest so far
+	BidDecrement float64 `json:"bid_decrement"`
+}
+
+// SealedBids describes how a sealed-bid auction is priced
+type SealedBids struct {
+	// Pricing is "first_price" (the winner pays their bid) or
+	// "second_price" (the winner pays the runner-up's bid)
+	Pricing string `json:"pricing"`
+}
+
+// DutchPrice describes how the price of a Dutch auction falls
+type DutchPrice struct {
+	PriceStep       float64 `json:"price_step"`
+	IntervalSeconds int     `json:"interval_seconds"`
+	FloorPrice      float64 `json:"floor_price"`
+	// NextDropAt is absent once the price reaches the floor or is accepted
+	NextDropAt *time.Time `json:"next_drop_at,omitempty"`
+}
+
+// ItemDetail is a single auction together with its bid history
+type ItemDetail struct {
+	Item ItemSummary `json:"item"`
+	Bids []BidView   `json:"bids"`
+	// LotItems catalogues what a lot contains
+	LotItems []LotItem `json:"lot_items,omitempty"`
+}
+
+// SellerAuction is an auction as shown on the seller dashboard
+type SellerAuction struct {
+	ItemSummary
+	Bids []BidView `json:"bids"`
+}
+
+// ItemRevision is one edit of a listing, listing each field that changed
+type ItemRevision struct {
+	Revision int                    `json:"revision"`
+	EditedAt time.Time              `json:"edited_at"`
+	Changes  map[string]AuditChange `json:"changes"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LiveSession is a live auction: an auctioneer opens its lots one at a
+// time and hammers each down
+type LiveSession struct {
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	AuctioneerID int       `json:"auctioneer_id"`
+	Auctioneer   string    `json:"auctioneer"`
+	StartsAt     time.Time `json:"starts_at"`
+	BidIncrement float64   `json:"bid_increment"`
+	// Status is "scheduled", "running" or "closed"
+	Status string `json:"status"`
+	// CurrentItemID is the lot on the block, if any
+	CurrentItemID *int `json:"current_item_id,omitempty"`
+	// Lots is the catalogue in order; session listings leave it out
+	Lots []ItemSummary `json:"lots,omitempty"`
+}
@@ -0,0 +1,86 @@
+package models
+
+// MessageResponse is returned by endpoints that only report success
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// TokenResponse carries a short-lived access token and the refresh token
+// that renews it. ExpiresIn is the access token lifetime in seconds.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// AccountProfile describes an account and the roles it holds. Email is
+// empty for admin accounts that sign in with a username.
+type AccountProfile struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	Email         string   `json:"email,omitempty"`
+	Username      string   `json:"username,omitempty"`
+	Roles         []string `json:"roles"`
+	EmailVerified bool     `json:"email_verified"`
+}
+
+// AuthResponse is returned by login and seller registration. RecoveryCodes
+// is only set when the login completed a first two-factor enrollment.
+type AuthResponse struct {
+	Message string `json:"message,omitempty"`
+	TokenResponse
+	Account       AccountProfile `json:"account"`
+	RecoveryCodes []string       `json:"recovery_codes,omitempty"`
+}
+
+// MFAChallengeResponse is returned instead of tokens when a login needs a
+// second factor. The MFA token identifies the half-finished login.
+type MFAChallengeResponse struct {
+	MFARequired        bool   `json:"mfa_required"`
+	MFAToken           string `json:"mfa_token"`
+	ExpiresIn          int    `json:"expires_in"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
+
+// MFAEnrollmentResponse carries a new TOTP secret and its otpauth URI
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse lists freshly generated single-use recovery codes.
+// They are shown once and only stored hashed.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// RevokeSessionsResponse reports how many sessions an admin revoked
+type RevokeSessionsResponse struct {
+	Message string `json:"message"`
+	Revoked int    `json:"revoked"`
+}
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrorBody carries a stable machine-readable code alongside a human message
+type ErrorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id"`
+}
+
+// ErrorResponse is the envelope of every error returned by the API
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// HealthResponse is returned by the liveness and readiness probes
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
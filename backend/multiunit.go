@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatMultiUnit auctions sell several identical units, each bid asking
+// for a number of them at a unit price
+const formatMultiUnit = "multi_unit"
+
+// Multi-unit clearing: every winner pays the lowest winning unit price, or
+// each pays their own
+const (
+	clearingUniform  = "uniform"
+	clearingPayAsBid = "pay_as_bid"
+)
+
+// clearingPriceSQL is the unit price a new bid on a multi-unit auction i
+// must beat, with bids b joined: the starting price while units are left
+// over, then the lowest bid that would still get units. Bids rank by price,
+// then by time.
+const clearingPriceSQL = `CASE
+			WHEN COALESCE(SUM(b.quantity), 0) < i.quantity THEN i.starting_price
+			ELSE (SELECT MIN(r.bid_amount) FROM (
+				SELECT s.bid_amount, SUM(s.quantity) OVER (ORDER BY s.bid_amount DESC, s.bid_time, s.id) - s.quantity AS ahead
+				FROM bids s WHERE s.item_id = i.id
+			) r WHERE r.ahead < i.quantity)
+		END`
+
+// multiUnitTerms configures a new multi-unit auction
+type multiUnitTerms struct {
+	Quantity int    `json:"quantity" binding:"required,min=1,max=10000"`
+	Clearing string `json:"clearing" binding:"required,oneof=uniform pay_as_bid"`
+}
+
+// columns returns the values stored in quantity and clearing; other
+// formats sell a single unit and have no clearing rule
+func (t *multiUnitTerms) columns() (quantity int, clearing any) {
+	if t == nil {
+		return 1, nil
+	}
+	return t.Quantity, t.Clearing
+}
+
+// outbidUnits requires a bid for units of a multi-unit auction to fit the
+// quantity on sale and beat the current clearing price
+func outbidUnits(ctx context.Context, q queryer, itemID string, amount float64, units int) (*bidRejection, error) {
+	var quantity int
+	var price float64
+	err := q.QueryRowContext(ctx, `
+		SELECT i.quantity, `+clearingPriceSQL+`
+		FROM items i LEFT JOIN bids b ON b.item_id = i.id
+		WHERE i.id = $1
+		GROUP BY i.id
+	`, itemID).Scan(&quantity, &price)
+	if err != nil {
+		return nil, err
+	}
+	if units > quantity {
+		return &bidRejection{http.StatusBadRequest, codeValidationFailed, bidRejectedInvalid, "Invalid input",
+			[]models.FieldError{{Field: "quantity", Message: fmt.Sprintf("must be at most %d", quantity)}}}, nil
+	}
+	if amount <= price {
+		return &bidRejection{http.StatusUnprocessableEntity, codeBidTooLow, bidRejectedTooLow, "Bid must be higher than the clearing price",
+			[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must be greater than %.2f", price)}}}, nil
+	}
+	return nil, nil
+}
+
+// placeUnitsBid records a bid for units of a multi-unit auction. The
+// clearing price depends on every bid, so the item row is locked and the
+// bid checked again before it goes in, or two bids could both clear the
+// same price.
+func placeUnitsBid(c *gin.Context, itemID string, bidderID int, amount float64, units int) {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	defer tx.Rollback()
+
+	var open bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT status IN ('active', 'upcoming') AND start_time <= NOW() AND end_time > NOW()
+		 FROM items WHERE id = $1 FOR UPDATE`, itemID).Scan(&open)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if !open {
+		bidRejected(bidRejectedClosed)
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Auction has ended")
+		return
+	}
+	rej, err := outbidUnits(ctx, tx, itemID, amount, units)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if rej != nil {
+		bidRejected(rej.reason)
+		respondError(c, rej.status, rej.code, rej.message, rej.details...)
+		return
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO bids (item_id, bidder_id, bid_amount, quantity) VALUES ($1, $2, $3, $4)",
+		itemID, bidderID, amount, units)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	bidAccepted()
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Bid placed"})
+}
+
+// allocateUnits settles a closed multi-unit auction. Bids are filled in
+// order of price, then time, until the quantity runs out; the bid at the
+// margin gets whatever is left and later bids get nothing. Under uniform
+// clearing every winner pays the lowest winning price, otherwise their own.
+func allocateUnits(ctx context.Context, tx *sql.Tx, itemID, quantity int, clearing string) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, quantity, bid_amount FROM bids WHERE item_id = $1 ORDER BY bid_amount DESC, bid_time, id", itemID)
+	if err != nil {
+		return err
+	}
+	var bids []unitFill
+	for rows.Next() {
+		var f unitFill
+		if err := rows.Scan(&f.bidID, &f.units, &f.price); err != nil {
+			rows.Close()
+			return err
+		}
+		bids = append(bids, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, f := range fillUnits(bids, quantity, clearing) {
+		var price any
+		if f.units > 0 {
+			price = f.price
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE bids SET units_won = $2, unit_price = $3 WHERE id = $1",
+			f.bidID, f.units, price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unitFill is a bid's share of a multi-unit lot: the units and unit price
+// it bid for going in, the units it won and the unit price it pays coming out
+type unitFill struct {
+	bidID, units int
+	price        float64
+}
+
+// fillUnits hands quantity units to bids, best first, each taking as many
+// as it asked for while units last. Winners pay their own price, or under
+// uniform clearing the lowest winning price; losers get no units.
+func fillUnits(bids []unitFill, quantity int, clearing string) []unitFill {
+	fills := make([]unitFill, len(bids))
+	remaining := quantity
+	var lowest float64
+	for i, b := range bids {
+		fills[i] = unitFill{bidID: b.bidID, units: min(b.units, remaining), price: b.price}
+		remaining -= fills[i].units
+		if fills[i].units > 0 {
+			lowest = b.price
+		}
+	}
+	for i := range fills {
+		switch {
+		case fills[i].units == 0:
+			fills[i].price = 0
+		case clearing == clearingUniform:
+			fills[i].price = lowest
+		}
+	}
+	return fills
+}
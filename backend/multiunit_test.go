@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFillUnits(t *testing.T) {
+	// Bids arrive best first: highest price, then earliest
+	bids := []unitFill{
+		{bidID: 1, units: 3, price: 30},
+		{bidID: 2, units: 4, price: 25},
+		{bidID: 3, units: 2, price: 20},
+		{bidID: 4, units: 1, price: 15},
+	}
+	tests := []struct {
+		name     string
+		bids     []unitFill
+		quantity int
+		clearing string
+		want     []unitFill
+	}{
+		{"pay as bid with a partial fill at the margin", bids, 8, clearingPayAsBid, []unitFill{
+			{1, 3, 30}, {2, 4, 25}, {3, 1, 20}, {4, 0, 0},
+		}},
+		{"uniform price is the lowest winning bid", bids, 8, clearingUniform, []unitFill{
+			{1, 3, 20}, {2, 4, 20}, {3, 1, 20}, {4, 0, 0},
+		}},
+		{"quantity runs out on a whole bid", bids, 7, clearingUniform, []unitFill{
+			{1, 3, 25}, {2, 4, 25}, {3, 0, 0}, {4, 0, 0},
+		}},
+		{"every bid filled with units to spare", bids, 20, clearingUniform, []unitFill{
+			{1, 3, 15}, {2, 4, 15}, {3, 2, 15}, {4, 1, 15},
+		}},
+		{"single unit goes to the best bid", bids, 1, clearingPayAsBid, []unitFill{
+			{1, 1, 30}, {2, 0, 0}, {3, 0, 0}, {4, 0, 0},
+		}},
+		{"no bids", nil, 5, clearingUniform, []unitFill{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fillUnits(tt.bids, tt.quantity, tt.clearing); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fillUnits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
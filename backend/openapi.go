@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"strings"
+
+	"auction-system/models"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.yaml
+var openAPIDoc []byte
+
+func init() {
+	openapi3.DefineStringFormatValidator("email", openapi3.NewRegexpFormatValidator(openapi3.FormatOfStringForEmail))
+}
+
+// loadOpenAPI parses and validates the embedded OpenAPI document
+func loadOpenAPI() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(openAPIDoc)
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// serveOpenAPI returns the API document as JSON
+func serveOpenAPI(spec *openapi3.T) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	}
+}
+
+// openAPIPath converts a Gin route pattern such as /api/auctions/:itemId
+// into its OpenAPI form /api/auctions/{itemId}
+func openAPIPath(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// specRoute looks up the documented operation for the matched Gin route
+func specRoute(spec *openapi3.T, c *gin.Context) *routers.Route {
+	path := openAPIPath(c.FullPath())
+	pathItem := spec.Paths.Find(path)
+	if pathItem == nil {
+		return nil
+	}
+	op := pathItem.GetOperation(c.Request.Method)
+	if op == nil {
+		return nil
+	}
+	return &routers.Route{
+		Spec:      spec,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    c.Request.Method,
+		Operation: op,
+	}
+}
+
+// validateRequest rejects requests whose parameters or body do not match
+// the OpenAPI document. Authentication is left to authMiddleware.
+func validateRequest(spec *openapi3.T) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		MultiError:         true,
+	}
+	return func(c *gin.Context) {
+		route := specRoute(spec, c)
+		if route == nil {
+			c.Next()
+			return
+		}
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			abortError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input", validationDetails(err)...)
+			return
+		}
+		c.Next()
+	}
+}
+
+// validationDetails flattens a request validation error into per-field
+// problems a client can display next to its inputs
+func validationDetails(err error) []models.FieldError {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) && reqErr.Parameter != nil {
+		return []models.FieldError{{Field: reqErr.Parameter.Name, Message: validationReason(reqErr)}}
+	}
+
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var details []models.FieldError
+		for _, e := range multi {
+			details = append(details, validationDetails(e)...)
+		}
+		return details
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		field := strings.Join(schemaErr.JSONPointer(), ".")
+		if field == "" {
+			field = "body"
+		}
+		return []models.FieldError{{Field: field, Message: schemaErr.Reason}}
+	}
+
+	return []models.FieldError{{Field: "body", Message: validationReason(err)}}
+}
+
+// validationReason extracts a short, client-facing reason from a
+// validation error
+func validationReason(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.Reason != "" {
+			return reqErr.Reason
+		}
+		if reqErr.Err != nil {
+			return reqErr.Err.Error()
+		}
+	}
+	return err.Error()
+}
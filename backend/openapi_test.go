@@ -0,0 +1,238 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// responseSchemas maps every documented response schema to the Go type the
+// handlers actually encode
+var responseSchemas = map[string]any{
+	"ErrorResponse":          models.ErrorResponse{},
+	"ErrorBody":              models.ErrorBody{},
+	"FieldError":             models.FieldError{},
+	"HealthResponse":         models.HealthResponse{},
+	"MessageResponse":        models.MessageResponse{},
+	"TokenResponse":          models.TokenResponse{},
+	"AccountProfile":         models.AccountProfile{},
+	"AuthResponse":           models.AuthResponse{},
+	"AuditEvent":             models.AuditEvent{},
+	"AuditEventPage":         models.AuditEventPage{},
+	"RevokeSessionsResponse": models.RevokeSessionsResponse{},
+	"MFAChallengeResponse":   models.MFAChallengeResponse{},
+	"MFAEnrollmentResponse":  models.MFAEnrollmentResponse{},
+	"RecoveryCodesResponse":  models.RecoveryCodesResponse{},
+	"ItemSummary":            models.ItemSummary{},
+	"BidView":                models.BidView{},
+	"ItemDetail":             models.ItemDetail{},
+	"ItemRevision":           models.ItemRevision{},
+	"SellerAuction":          models.SellerAuction{},
+	"LiveSession":            models.LiveSession{},
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func mustLoadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	spec, err := loadOpenAPI()
+	if err != nil {
+		t.Fatalf("openapi.yaml is invalid: %v", err)
+	}
+	return spec
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	spec := mustLoadSpec(t)
+	r, err := setupRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routed := map[string]bool{}
+	for _, route := range r.Routes() {
+		key := route.Method + " " + openAPIPath(route.Path)
+		routed[key] = true
+		pathItem := spec.Paths.Find(openAPIPath(route.Path))
+		if pathItem == nil || pathItem.GetOperation(route.Method) == nil {
+			t.Errorf("route %s is not documented in openapi.yaml", key)
+		}
+	}
+
+	for path, pathItem := range spec.Paths.Map() {
+		for method := range pathItem.Operations() {
+			if key := method + " " + path; !routed[key] {
+				t.Errorf("openapi.yaml documents %s but no handler is registered", key)
+			}
+		}
+	}
+}
+
+func TestResponseSchemasMatchModels(t *testing.T) {
+	spec := mustLoadSpec(t)
+	for name, model := range responseSchemas {
+		ref := spec.Components.Schemas[name]
+		if ref == nil {
+			t.Errorf("schema %s is missing from openapi.yaml", name)
+			continue
+		}
+		compareSchema(t, name, ref.Value, reflect.TypeOf(model))
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	r, err := setupRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json returned %d", w.Code)
+	}
+	if _, err := openapi3.NewLoader().LoadFromData(w.Body.Bytes()); err != nil {
+		t.Fatalf("served document does not parse: %v", err)
+	}
+}
+
+// compareSchema checks that the JSON encoding of typ has exactly the
+// properties and primitive types the schema describes
+func compareSchema(t *testing.T, where string, schema *openapi3.Schema, typ reflect.Type) {
+	t.Helper()
+	props := schemaProperties(schema)
+	fields := jsonFields(typ)
+
+	var missing, extra []string
+	for name := range fields {
+		if _, ok := props[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	for name := range props {
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	if len(extra) > 0 {
+		t.Errorf("%s: fields not in schema: %s", where, strings.Join(extra, ", "))
+	}
+	if len(missing) > 0 {
+		t.Errorf("%s: schema properties not produced: %s", where, strings.Join(missing, ", "))
+	}
+
+	for name, field := range fields {
+		prop, ok := props[name]
+		if !ok {
+			continue
+		}
+		checkType(t, where+"."+name, prop, field)
+	}
+}
+
+func checkType(t *testing.T, where string, schema *openapi3.Schema, typ reflect.Type) {
+	t.Helper()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	want := schemaType(schema)
+	switch {
+	case typ == reflect.TypeOf(time.Time{}):
+		if want != "string" || schema.Format != "date-time" {
+			t.Errorf("%s: time.Time must be documented as a date-time string", where)
+		}
+	case typ.Kind() == reflect.Struct:
+		if want != "object" {
+			t.Errorf("%s: struct documented as %q", where, want)
+			return
+		}
+		compareSchema(t, where, schema, typ)
+	case typ.Kind() == reflect.Slice:
+		if want != "array" {
+			t.Errorf("%s: slice documented as %q", where, want)
+			return
+		}
+		checkType(t, where+"[]", schema.Items.Value, typ.Elem())
+	case typ.Kind() == reflect.String:
+		if want != "string" {
+			t.Errorf("%s: string documented as %q", where, want)
+		}
+	case typ.Kind() == reflect.Bool:
+		if want != "boolean" {
+			t.Errorf("%s: bool documented as %q", where, want)
+		}
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		if want != "integer" {
+			t.Errorf("%s: integer documented as %q", where, want)
+		}
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		if want != "number" {
+			t.Errorf("%s: float documented as %q", where, want)
+		}
+	}
+}
+
+// schemaType returns the declared type, treating allOf compositions as objects
+func schemaType(schema *openapi3.Schema) string {
+	if len(schema.AllOf) > 0 {
+		return "object"
+	}
+	if schema.Type == nil || len(*schema.Type) == 0 {
+		return ""
+	}
+	return (*schema.Type)[0]
+}
+
+// schemaProperties flattens the properties of a schema and its allOf parts
+func schemaProperties(schema *openapi3.Schema) map[string]*openapi3.Schema {
+	props := map[string]*openapi3.Schema{}
+	for _, part := range schema.AllOf {
+		for name, prop := range schemaProperties(part.Value) {
+			props[name] = prop
+		}
+	}
+	for name, ref := range schema.Properties {
+		props[name] = ref.Value
+	}
+	return props
+}
+
+// jsonFields returns the JSON field names of a struct, flattening embedded
+// structs the way encoding/json does
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			for n, ft := range jsonFields(f.Type) {
+				fields[n] = ft
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"auction-system/config"
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Purposes of single-use account tokens
+const (
+	purposePasswordReset     = "password_reset"
+	purposeEmailVerification = "email_verification"
+)
+
+// resetRequestCooldown stops the reset endpoint being used to flood an inbox
+const resetRequestCooldown = time.Minute
+
+// Settings for recovery emails; set by setupRouter
+var (
+	accountPolicy = config.NewAccountPolicy()
+	appURL        = config.NewMailConfig().AppURL
+)
+
+// issueAccountToken stores a new single-use token for the account and
+// invalidates any earlier unused token with the same purpose
+func issueAccountToken(ctx context.Context, purpose string, id int, ttl time.Duration) (string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE account_tokens SET used_at = NOW()
+		WHERE purpose = $1 AND account_id = $2 AND used_at IS NULL
+	`, purpose, id)
+	if err != nil {
+		return "", err
+	}
+	raw := randomToken(32)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO account_tokens (token_hash, purpose, account_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, hashToken(raw), purpose, id, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return raw, tx.Commit()
+}
+
+// consumeAccountToken marks a token used and returns its account. It
+// reports sql.ErrNoRows for unknown, expired or already used tokens.
+func consumeAccountToken(ctx context.Context, ex queryer, purpose, raw string) (id int, err error) {
+	err = ex.QueryRowContext(ctx, `
+		UPDATE account_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING account_id
+	`, hashToken(raw), purpose).Scan(&id)
+	return id, err
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// frontendLink builds a link into the React app carrying a token
+func frontendLink(path, token string) string {
+	return appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail issues a verification token and mails the link
+func sendVerificationEmail(ctx context.Context, id int, name, email string) error {
+	token, err := issueAccountToken(ctx, purposeEmailVerification, id, accountPolicy.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	sendMailAsync(ctx, mailMessage{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. Until you confirm, bids above %.2f are not accepted.\n",
+			name, frontendLink("/verify-email", token), accountPolicy.EmailVerificationTTL, accountPolicy.UnverifiedBidLimit),
+	})
+	return nil
+}
+
+// requestPasswordReset mails a reset link if the account exists. The
+// response is the same either way so it cannot be used to probe for accounts.
+func requestPasswordReset(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email,max=255"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	accepted := models.MessageResponse{Message: "If the account exists, a reset link has been sent"}
+
+	var id int
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT id, name FROM accounts WHERE lower(email) = lower($1)", req.Email).Scan(&id, &name)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not request password reset")
+		return
+	}
+
+	var recent bool
+	err = db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM account_tokens
+		WHERE purpose = $1 AND account_id = $2 AND created_at > $3)
+	`, purposePasswordReset, id, time.Now().Add(-resetRequestCooldown)).Scan(&recent)
+	if err != nil {
+		respondDBError(c, err, "Could not request password reset")
+		return
+	}
+	if recent {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	token, err := issueAccountToken(ctx, purposePasswordReset, id, accountPolicy.PasswordResetTTL)
+	if err != nil {
+		respondDBError(c, err, "Could not request password reset")
+		return
+	}
+	sendMailAsync(ctx, mailMessage{
+		To:      req.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for this account. "+
+			"If it was you, open this link to choose a new one:\n\n%s\n\n"+
+			"The link expires in %s. If you did not ask for this, ignore this email.\n",
+			name, frontendLink("/reset-password", token), accountPolicy.PasswordResetTTL),
+	})
+	slog.InfoContext(ctx, "password reset requested", "account_id", id)
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// confirmPasswordReset sets a new password using a reset token. Every
+// session of the account is revoked and any login lockout is cleared.
+func confirmPasswordReset(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required,max=200"`
+		Password string `json:"password" binding:"required,password"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		slog.ErrorContext(ctx, "hashing password failed", "error", err)
+		respondError(c, http.StatusInternalServerError, codeInternal, "Server error")
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not reset password")
+		return
+	}
+	defer tx.Rollback()
+
+	id, err := consumeAccountToken(ctx, tx, purposePasswordReset, req.Token)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusBadRequest, codeInvalidToken, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not reset password")
+		return
+	}
+
+	// Following the emailed link proves the address too
+	var email, username string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE accounts
+		SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 RETURNING COALESCE(email, ''), COALESCE(username, '')
+	`, id, string(hash)).Scan(&email, &username)
+	if err != nil {
+		respondDBError(c, err, "Could not reset password")
+		return
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = NOW(), revoked_reason = $2
+		WHERE account_id = $1 AND revoked_at IS NULL
+	`, id, revokedPasswordReset)
+	if err != nil {
+		respondDBError(c, err, "Could not reset password")
+		return
+	}
+	if err := clearAccountLockout(ctx, tx, email, username); err != nil {
+		respondDBError(c, err, "Could not reset password")
+		return
+	}
+	audit(c, tx, auditEvent{action: auditPasswordReset, actor: id, targetType: auditTargetAccount, targetID: id})
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not reset password")
+		return
+	}
+
+	slog.InfoContext(ctx, "password reset", "account_id", id)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Password has been reset; please log in again"})
+}
+
+// verifyEmail marks an account's email as verified using a token from the
+// verification email
+func verifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required,max=200"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not verify email")
+		return
+	}
+	defer tx.Rollback()
+
+	id, err := consumeAccountToken(ctx, tx, purposeEmailVerification, req.Token)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusBadRequest, codeInvalidToken, "Invalid or expired verification token")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not verify email")
+		return
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE accounts SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		respondDBError(c, err, "Could not verify email")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not verify email")
+		return
+	}
+
+	slog.InfoContext(ctx, "email verified", "account_id", id)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Email verified"})
+}
+
+// resendVerification mails a fresh verification link to the caller
+func resendVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := accountID(c)
+
+	account, err := loadAccount(ctx, db, id)
+	if err != nil {
+		respondDBError(c, err, "Could not send verification email")
+		return
+	}
+	if account.Email == "" {
+		respondError(c, http.StatusForbidden, codeForbidden, "This account has no email address")
+		return
+	}
+	if account.EmailVerified {
+		respondError(c, http.StatusConflict, codeConflict, "Email already verified")
+		return
+	}
+	if err := sendVerificationEmail(ctx, id, account.Name, account.Email); err != nil {
+		respondDBError(c, err, "Could not send verification email")
+		return
+	}
+	c.JSON(http.StatusAccepted, models.MessageResponse{Message: "Verification email sent"})
+}
+
+// emailVerified reports whether a bidder has confirmed their email
+func emailVerified(ctx context.Context, id int) (bool, error) {
+	var verifiedAt sql.NullTime
+	err := db.QueryRowContext(ctx, "SELECT email_verified_at FROM accounts WHERE id = $1", id).Scan(&verifiedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return verifiedAt.Valid, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// relistTerms asks for an auction that ends unsold to be listed again,
+// up to MaxRelists times, each time PriceDropPercent cheaper
+type relistTerms struct {
+	MaxRelists       int     `json:"max_relists" binding:"required,min=1,max=10"`
+	PriceDropPercent float64 `json:"price_drop_percent" binding:"finite,gte=0,lt=100"`
+}
+
+// columns returns the values stored in relist_max and relist_price_drop;
+// listings without terms are never relisted
+func (t *relistTerms) columns() (maxRelists int, priceDrop float64) {
+	if t == nil {
+		return 0, 0
+	}
+	return t.MaxRelists, t.PriceDropPercent
+}
+
+// relistItem lists an unsold auction again with the same terms, catalogue
+// and length, opening now. The starting price drops by the listing's
+// percentage, rounded to the cent; a Dutch floor above the new price comes
+// down with it. The copy points back through relisted_from and counts one
+// more relist, so a chain stops after relist_max.
+func relistItem(ctx context.Context, tx *sql.Tx, itemID int) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO items (name, description, starting_price, seller_id, start_time, end_time, status,
+		                   format, price_step, price_interval_seconds, floor_price, sealed_pricing, bid_decrement,
+		                   quantity, clearing, relist_max, relist_price_drop, relist_count, relisted_from)
+		SELECT i.name, i.description, p.price, i.seller_id, NOW(), NOW() + (i.end_time - i.start_time), 'active',
+		       i.format, i.price_step, i.price_interval_seconds, LEAST(i.floor_price, p.price), i.sealed_pricing, i.bid_decrement,
+		       i.quantity, i.clearing, i.relist_max, i.relist_price_drop, i.relist_count + 1, i.id
+		FROM items i,
+		     LATERAL (SELECT GREATEST(ROUND(i.starting_price * (100 - i.relist_price_drop) / 100, 2), 0.01) AS price) p
+		WHERE i.id = $1
+		RETURNING id
+	`, itemID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO lot_items (lot_id, position, name, description, image_urls)
+		SELECT $2, position, name, description, image_urls FROM lot_items WHERE lot_id = $1
+	`, itemID, id)
+	return id, err
+}
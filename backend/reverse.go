@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatReverse auctions are posted by a buyer with a ceiling price and
+// bid down by sellers; the lowest bid wins
+const formatReverse = "reverse"
+
+// createReverseAuction lets a buyer ask sellers to compete for an order.
+// The ceiling is stored as the starting price and the buyer as the owner,
+// so the reverse auction shares the lifecycle, edit, cancel and stream
+// handling of forward auctions.
+func createReverseAuction(c *gin.Context) {
+	var req struct {
+		Name         string     `json:"name" binding:"required,notblank,max=200"`
+		Description  string     `json:"description" binding:"max=5000"`
+		CeilingPrice float64    `json:"ceiling_price" binding:"required,finite,gt=0,lte=99999999.99"`
+		BidDecrement float64    `json:"bid_decrement" binding:"required,finite,gt=0,lte=99999999.99"`
+		StartTime    *time.Time `json:"start_time" binding:"omitempty,auction_start"`
+		EndTime      time.Time  `json:"end_time" binding:"required,auction_end"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.BidDecrement >= req.CeilingPrice {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input",
+			models.FieldError{Field: "bid_decrement", Message: fmt.Sprintf("must be less than the ceiling price %.2f", req.CeilingPrice)})
+		return
+	}
+	ctx := c.Request.Context()
+	status := "active"
+	created := map[string]any{
+		"name":           req.Name,
+		"description":    req.Description,
+		"starting_price": req.CeilingPrice,
+		"bid_decrement":  req.BidDecrement,
+		"end_time":       req.EndTime,
+		"format":         formatReverse,
+	}
+	if req.StartTime != nil {
+		status = "upcoming"
+		created["start_time"] = *req.StartTime
+	}
+	var id int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO items (name, description, starting_price, seller_id, start_time, end_time, status, format, bid_decrement)
+		VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), $6, $7, 'reverse', $8)
+		RETURNING id
+	`, req.Name, req.Description, req.CeilingPrice, accountID(c), req.StartTime, req.EndTime, status, req.BidDecrement).Scan(&id)
+	if err != nil {
+		respondDBError(c, err, "Could not create reverse auction")
+		return
+	}
+	audit(c, db, auditEvent{action: auditItemCreated, targetType: auditTargetItem, targetID: id,
+		diff: auditDiff(nil, created)})
+
+	item, err := loadItem(ctx, fmt.Sprint(id))
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// bidderRole is the role an account needs to bid in an auction format:
+// sellers compete in reverse auctions, bidders in every other format
+func bidderRole(format string) string {
+	if format == formatReverse {
+		return roleSeller
+	}
+	return roleBidder
+}
+
+// undercut requires a bid in a reverse auction to come in at or under the
+// ceiling, and then at least the decrement below the lowest bid so far
+func undercut(ctx context.Context, q queryer, itemID string, amount float64) (*bidRejection, error) {
+	var ceiling, decrement float64
+	var lowest *float64
+	err := q.QueryRowContext(ctx, `
+		SELECT i.starting_price, i.bid_decrement, MIN(b.bid_amount)
+		FROM items i LEFT JOIN bids b ON b.item_id = i.id
+		WHERE i.id = $1
+		GROUP BY i.id
+	`, itemID).Scan(&ceiling, &decrement, &lowest)
+	if err != nil {
+		return nil, err
+	}
+	if lowest == nil && amount > ceiling {
+		return &bidRejection{http.StatusUnprocessableEntity, codeBidTooHigh, bidRejectedTooHigh, "Bid must not exceed the ceiling price",
+			[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must be at most %.2f", ceiling)}}}, nil
+	}
+	// Compare in cents so a decrement like 0.10 is not lost to rounding
+	if lowest != nil && int64(amount*100+0.5) > int64((*lowest-decrement)*100+0.5) {
+		return &bidRejection{http.StatusUnprocessableEntity, codeBidTooHigh, bidRejectedTooHigh, "Bid must undercut the current lowest bid",
+			[]models.FieldError{{Field: "bid_amount", Message: fmt.Sprintf("must be at most %.2f", *lowest-decrement)}}}, nil
+	}
+	return nil, nil
+}
+
+// placeReverseBid records a seller's bid in a reverse auction. The item row
+// is locked and the bid checked again before it goes in, or two sellers
+// could both undercut the same lowest bid.
+func placeReverseBid(c *gin.Context, itemID string, bidderID int, amount float64) {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	defer tx.Rollback()
+
+	var open bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT status IN ('active', 'upcoming') AND start_time <= NOW() AND end_time > NOW()
+		 FROM items WHERE id = $1 FOR UPDATE`, itemID).Scan(&open)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if !open {
+		bidRejected(bidRejectedClosed)
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Auction has ended")
+		return
+	}
+	rej, err := undercut(ctx, tx, itemID, amount)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if rej != nil {
+		bidRejected(rej.reason)
+		respondError(c, rej.status, rej.code, rej.message, rej.details...)
+		return
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO bids (item_id, bidder_id, bid_amount) VALUES ($1, $2, $3)",
+		itemID, bidderID, amount)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	bidAccepted()
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Bid placed"})
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// updateItem lets the seller edit a running or upcoming auction. Before the
+// first bid every field can change, the start time only until the auction
+// opens; afterwards only the description, and the end time may only move
+// later, so bidders are never caught out. Each edit is kept as a revision
+// that anyone can read. Drafts are not public, so their edits are not
+// kept as revisions and their timing is only set when they are published.
+// Lots of a live session are timed by the auctioneer and can only be edited
+// until they are opened.
+func updateItem(c *gin.Context) {
+	var req struct {
+		Name          *string    `json:"name" binding:"omitempty,notblank,max=200"`
+		Description   *string    `json:"description" binding:"omitempty,max=5000"`
+		StartingPrice *float64   `json:"starting_price" binding:"omitempty,finite,gt=0,lte=99999999.99"`
+		StartTime     *time.Time `json:"start_time" binding:"omitempty,auction_start"`
+		EndTime       *time.Time `json:"end_time" binding:"omitempty,auction_end"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	itemID := c.Param("itemId")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not update auction")
+		return
+	}
+	defer tx.Rollback()
+
+	var name, description, status string
+	var startingPrice float64
+	var startTime, endTime sql.NullTime
+	var sellerID int
+	var live bool
+	var terms listingTerms
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, COALESCE(description, ''), starting_price, start_time, end_time, seller_id, COALESCE(status, 'active'),
+		       session_id IS NOT NULL, `+listingTermsSQL+`
+		FROM items WHERE id = $1 FOR UPDATE
+	`, itemID).Scan(append([]any{&name, &description, &startingPrice, &startTime, &endTime, &sellerID, &status, &live},
+		terms.dest()...)...)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Auction not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not update auction")
+		return
+	}
+	if sellerID != accountID(c) {
+		respondError(c, http.StatusForbidden, codeForbidden, "Only the account that listed this auction can edit it")
+		return
+	}
+	if status == "draft" && (req.StartTime != nil || req.EndTime != nil) {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Drafts get their timing when published",
+			models.FieldError{Field: "end_time", Message: "is set when the draft is published"})
+		return
+	}
+	if status != "draft" && ((status != "active" && status != "upcoming") || (live && status != "upcoming") ||
+		(endTime.Valid && endTime.Time.Before(time.Now()))) {
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Only running or upcoming auctions can be edited")
+		return
+	}
+	if live && (req.StartTime != nil || req.EndTime != nil) {
+		respondError(c, http.StatusConflict, codeConflict, "Session lots are timed by the session",
+			models.FieldError{Field: "end_time", Message: "is set when the auctioneer hammers the lot down"})
+		return
+	}
+	if req.StartTime != nil && !startTime.Time.After(time.Now()) {
+		respondError(c, http.StatusConflict, codeConflict, "Auction has already started",
+			models.FieldError{Field: "start_time", Message: "can only change before the auction starts"})
+		return
+	}
+
+	before := map[string]any{
+		"name":           name,
+		"description":    description,
+		"starting_price": startingPrice,
+		"start_time":     startTime.Time.UTC(),
+		"end_time":       endTime.Time.UTC(),
+	}
+	after := maps.Clone(before)
+	if req.Name != nil {
+		after["name"] = *req.Name
+	}
+	if req.Description != nil {
+		after["description"] = *req.Description
+	}
+	if req.StartingPrice != nil {
+		after["starting_price"] = *req.StartingPrice
+	}
+	// TIMESTAMP columns keep microseconds
+	if req.StartTime != nil {
+		after["start_time"] = req.StartTime.UTC().Truncate(time.Microsecond)
+	}
+	if req.EndTime != nil {
+		after["end_time"] = req.EndTime.UTC().Truncate(time.Microsecond)
+	}
+	diff := auditDiff(before, after)
+	if _, changed := diff["starting_price"]; changed {
+		if details := terms.problems(after["starting_price"].(float64)); len(details) > 0 {
+			respondError(c, http.StatusBadRequest, codeValidationFailed, "Starting price conflicts with the auction's terms", details...)
+			return
+		}
+		// A running Dutch auction's price and a reverse auction's ceiling
+		// are what bidders are already acting on
+		if status != "draft" && (terms.format == formatDutch || terms.format == formatReverse) && !startTime.Time.After(time.Now()) {
+			respondError(c, http.StatusConflict, codeConflict, "Auction has already started",
+				models.FieldError{Field: "starting_price", Message: "can only change before the auction starts"})
+			return
+		}
+	}
+	if status == "draft" {
+		updateDraft(c, tx, itemID, diff, after)
+		return
+	}
+
+	// The end_time tag measures from now unless the request moves the
+	// start, so check the length of a scheduled auction here
+	newStart, newEnd := after["start_time"].(time.Time), after["end_time"].(time.Time)
+	if length := newEnd.Sub(newStart); !live && newStart.After(time.Now()) &&
+		(length < auctionRules.MinDuration || length > auctionRules.MaxDuration) {
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input",
+			models.FieldError{Field: "end_time", Message: fmt.Sprintf("must be between %s and %s after the start", auctionRules.MinDuration, auctionRules.MaxDuration)})
+		return
+	}
+
+	var bids int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM bids WHERE item_id = $1", itemID).Scan(&bids); err != nil {
+		respondDBError(c, err, "Could not update auction")
+		return
+	}
+	if bids > 0 {
+		var details []models.FieldError
+		for _, field := range []string{"name", "starting_price"} {
+			if _, changed := diff[field]; changed {
+				details = append(details, models.FieldError{Field: field, Message: "cannot change once bids have been placed"})
+			}
+		}
+		if newEnd.Before(endTime.Time) {
+			details = append(details, models.FieldError{Field: "end_time", Message: "can only be extended once bids have been placed"})
+		}
+		if len(details) > 0 {
+			respondError(c, http.StatusConflict, codeConflict,
+				"Auction has bids; only the description can be edited and the end time extended", details...)
+			return
+		}
+	}
+
+	if len(diff) > 0 {
+		var end any = newEnd
+		if live {
+			end = nil
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE items SET name = $2, description = $3, starting_price = $4, start_time = $5, end_time = $6 WHERE id = $1",
+			itemID, after["name"], after["description"], after["starting_price"], newStart, end)
+		if err != nil {
+			respondDBError(c, err, "Could not update auction")
+			return
+		}
+		changes, err := json.Marshal(diff)
+		if err != nil {
+			respondDBError(c, err, "Could not update auction")
+			return
+		}
+		// The item row is locked, so numbering revisions with MAX is safe
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO item_revisions (item_id, revision, edited_by, changes)
+			SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3 FROM item_revisions WHERE item_id = $1
+		`, itemID, accountID(c), string(changes))
+		if err != nil {
+			respondDBError(c, err, "Could not update auction")
+			return
+		}
+		audit(c, tx, auditEvent{action: auditItemUpdated, targetType: auditTargetItem, targetID: itemID, diff: diff})
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not update auction")
+		return
+	}
+
+	item, err := loadItem(ctx, itemID)
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// updateDraft saves an edit to a draft. Drafts are not public yet, so the
+// change is audited but not kept as a revision.
+func updateDraft(c *gin.Context, tx *sql.Tx, itemID string, diff map[string]models.AuditChange, after map[string]any) {
+	ctx := c.Request.Context()
+	if len(diff) > 0 {
+		_, err := tx.ExecContext(ctx, "UPDATE items SET name = $2, description = $3, starting_price = $4 WHERE id = $1",
+			itemID, after["name"], after["description"], after["starting_price"])
+		if err != nil {
+			respondDBError(c, err, "Could not update draft")
+			return
+		}
+		audit(c, tx, auditEvent{action: auditItemUpdated, targetType: auditTargetItem, targetID: itemID, diff: diff})
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not update draft")
+		return
+	}
+	item, err := loadItem(ctx, itemID)
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// listItemRevisions returns every edit of an auction, oldest first
+func listItemRevisions(c *gin.Context) {
+	ctx := c.Request.Context()
+	itemID := c.Param("itemId")
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM items WHERE id = $1 AND status IS DISTINCT FROM 'draft')", itemID).Scan(&exists); err != nil {
+		respondDBError(c, err, "Could not fetch revisions")
+		return
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, codeNotFound, "Item not found")
+		return
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT revision, edited_at, changes FROM item_revisions WHERE item_id = $1 ORDER BY revision", itemID)
+	if err != nil {
+		respondDBError(c, err, "Could not fetch revisions")
+		return
+	}
+	defer rows.Close()
+	revisions := []models.ItemRevision{}
+	for rows.Next() {
+		var rev models.ItemRevision
+		var changes []byte
+		if err := rows.Scan(&rev.Revision, &rev.EditedAt, &changes); err != nil {
+			respondDBError(c, err, "Could not fetch revisions")
+			return
+		}
+		if err := json.Unmarshal(changes, &rev.Changes); err != nil {
+			respondDBError(c, err, "Could not fetch revisions")
+			return
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		respondDBError(c, err, "Could not fetch revisions")
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sealed-bid pricing: the winner pays their own bid, or the runner-up's
+const (
+	pricingFirstPrice  = "first_price"
+	pricingSecondPrice = "second_price"
+)
+
+// sealedPriceSQL is the price shown for a sealed-bid auction i with bids b
+// joined. It stays at the starting price until the auction ends so bids
+// cannot be inferred; afterwards it is what the winner pays. A lone
+// second-price bid pays the starting price.
+const sealedPriceSQL = `CASE
+			WHEN ` + itemStatusSQL + ` <> 'ended' THEN i.starting_price
+			WHEN i.sealed_pricing = 'second_price' THEN GREATEST(i.starting_price,
+				(SELECT s.bid_amount FROM bids s WHERE s.item_id = i.id ORDER BY s.bid_amount DESC OFFSET 1 LIMIT 1))
+			ELSE COALESCE(MAX(b.bid_amount), i.starting_price)
+		END`
+
+// sealedTerms configures a new sealed-bid auction
+type sealedTerms struct {
+	Pricing string `json:"pricing" binding:"required,oneof=first_price second_price"`
+}
+
+// column returns the value stored in sealed_pricing, which is NULL for
+// other formats
+func (t *sealedTerms) column() any {
+	if t == nil {
+		return nil
+	}
+	return t.Pricing
+}
+
+// revealedBids returns the bids on an item that may be shown to anyone.
+// Sealed bids stay hidden, from the seller too, until the auction ends;
+// until then only the count in the summary is public.
+func revealedBids(ctx context.Context, item models.ItemSummary) ([]models.BidView, error) {
+	if item.Sealed != nil && item.Status != "ended" {
+		return []models.BidView{}, nil
+	}
+	return itemBids(ctx, item.ID)
+}
+
+// placeSealedBid records a bidder's single sealed bid. The item row is
+// locked so two requests from the same bidder cannot both get in, and so
+// no bid lands after the auction closes and its bids are revealed.
+func placeSealedBid(c *gin.Context, itemID string, bidderID int, amount float64) {
+	ctx := c.Request.Context()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	defer tx.Rollback()
+
+	// The auction may have been settled since the bid was checked
+	var open, placed bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT i.status IN ('active', 'upcoming') AND i.start_time <= NOW() AND i.end_time > NOW(),
+		       EXISTS (SELECT 1 FROM bids WHERE item_id = i.id AND bidder_id = $2)
+		FROM items i WHERE i.id = $1 FOR UPDATE
+	`, itemID, bidderID).Scan(&open, &placed)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if !open {
+		bidRejected(bidRejectedClosed)
+		respondError(c, http.StatusConflict, codeAuctionClosed, "Auction has ended")
+		return
+	}
+	if placed {
+		bidRejected(bidRejectedDuplicate)
+		respondError(c, http.StatusConflict, codeConflict, "You have already placed a sealed bid on this auction")
+		return
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO bids (item_id, bidder_id, bid_amount) VALUES ($1, $2, $3)", itemID, bidderID, amount)
+	if err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		bidRejected(bidRejectedError)
+		respondDBError(c, err, "Could not place bid")
+		return
+	}
+	bidAccepted()
+	slog.InfoContext(ctx, "sealed bid placed", "item_id", itemID, "bidder_id", bidderID)
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Sealed bid placed; bids are revealed when the auction ends"})
+}
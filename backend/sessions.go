@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auction-system/config"
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+)
+
+// Reasons recorded when a session is revoked
+const (
+	revokedLogout = "logout"
+	revokedReuse  = "refresh_token_reuse"
+	revokedAdmin  = "admin_revoked"
+	// revokedPasswordReset ends every session when the password changes
+	revokedPasswordReset = "password_reset"
+)
+
+// authConfig holds token lifetimes; set by setupRouter
+var authConfig = config.NewAuthConfig()
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// randomToken returns n random bytes encoded for use in URLs and headers
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is how refresh tokens are stored, so a database leak does not
+// hand out usable credentials
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// accountClaims are the identity claims placed in an account's access
+// tokens. Roles are informational for clients; the server reads them from
+// the database on every request.
+func accountClaims(account models.AccountProfile) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":   strconv.Itoa(account.ID),
+		"name":  account.Name,
+		"roles": account.Roles,
+	}
+	if account.Email != "" {
+		claims["email"] = account.Email
+	}
+	if account.Username != "" {
+		claims["username"] = account.Username
+	}
+	return claims
+}
+
+// newAccessToken signs a short-lived access token bound to a session
+func newAccessToken(claims jwt.MapClaims, sessionID string) (string, error) {
+	now := time.Now()
+	claims["sid"] = sessionID
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(authConfig.AccessTokenTTL).Unix()
+	return tokenKeys.sign(claims)
+}
+
+// issueRefreshToken stores a new single-use refresh token for a session
+func issueRefreshToken(ctx context.Context, ex execer, sessionID string) (string, error) {
+	raw := randomToken(32)
+	_, err := ex.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, $3)",
+		hashToken(raw), sessionID, time.Now().Add(authConfig.RefreshTokenTTL))
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// startSession records a new login session and issues its first access
+// and refresh tokens
+func startSession(c *gin.Context, account models.AccountProfile) (models.TokenResponse, error) {
+	ctx := c.Request.Context()
+	sessionID := randomToken(24)
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO sessions (id, account_id, user_agent, ip) VALUES ($1, $2, $3, $4)",
+		sessionID, account.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	refresh, err := issueRefreshToken(ctx, db, sessionID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	access, err := newAccessToken(accountClaims(account), sessionID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return tokenResponse(access, refresh), nil
+}
+
+func tokenResponse(access, refresh string) models.TokenResponse {
+	return models.TokenResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(authConfig.AccessTokenTTL.Seconds()),
+	}
+}
+
+// revokeSession ends a session so neither its refresh token nor its access
+// tokens are accepted any more
+func revokeSession(ctx context.Context, ex execer, sessionID, reason string) error {
+	_, err := ex.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = NOW(), revoked_reason = $2 WHERE id = $1 AND revoked_at IS NULL",
+		sessionID, reason)
+	return err
+}
+
+// sessionAccount returns the account behind a session and the roles it
+// holds now, so role changes apply without waiting for tokens to expire.
+// active is false for unknown and revoked sessions.
+func sessionAccount(ctx context.Context, sessionID string) (id int, roles []string, active bool, err error) {
+	var revoked sql.NullTime
+	err = db.QueryRowContext(ctx, `
+		SELECT s.account_id, s.revoked_at,
+		       COALESCE(array_agg(r.role) FILTER (WHERE r.role IS NOT NULL), '{}')
+		FROM sessions s
+		LEFT JOIN account_roles r ON r.account_id = s.account_id
+		WHERE s.id = $1
+		GROUP BY s.id
+	`, sessionID).Scan(&id, &revoked, pq.Array(&roles))
+	if err == sql.ErrNoRows {
+		return 0, nil, false, nil
+	}
+	if err != nil {
+		return 0, nil, false, err
+	}
+	return id, roles, !revoked.Valid, nil
+}
+
+// refreshSession exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token works once; presenting one that was
+// already rotated means it was copied, so the whole session is revoked.
+func refreshSession(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required,max=200"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		respondDBError(c, err, "Could not refresh session")
+		return
+	}
+	defer tx.Rollback()
+
+	var sessionID string
+	var accountID int
+	var expiresAt time.Time
+	var usedAt, revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT rt.session_id, rt.expires_at, rt.used_at, s.account_id, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s
+	`, hashToken(req.RefreshToken)).Scan(&sessionID, &expiresAt, &usedAt, &accountID, &revokedAt)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusUnauthorized, codeInvalidToken, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not refresh session")
+		return
+	}
+
+	switch {
+	case revokedAt.Valid:
+		respondError(c, http.StatusUnauthorized, codeSessionRevoked, "Session has been revoked")
+		return
+	case usedAt.Valid:
+		if err := revokeSession(ctx, tx, sessionID, revokedReuse); err != nil {
+			respondDBError(c, err, "Could not refresh session")
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondDBError(c, err, "Could not refresh session")
+			return
+		}
+		slog.WarnContext(ctx, "refresh token reuse detected, session revoked",
+			"session_id", sessionID, "account_id", accountID)
+		respondError(c, http.StatusUnauthorized, codeTokenReused, "Refresh token already used; session revoked")
+		return
+	case time.Now().After(expiresAt):
+		respondError(c, http.StatusUnauthorized, codeTokenExpired, "Refresh token expired")
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = $1", hashToken(req.RefreshToken)); err != nil {
+		respondDBError(c, err, "Could not refresh session")
+		return
+	}
+	refresh, err := issueRefreshToken(ctx, tx, sessionID)
+	if err != nil {
+		respondDBError(c, err, "Could not refresh session")
+		return
+	}
+	account, err := loadAccount(ctx, tx, accountID)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusUnauthorized, codeInvalidToken, "Account no longer exists")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not refresh session")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondDBError(c, err, "Could not refresh session")
+		return
+	}
+
+	access, err := newAccessToken(accountClaims(account), sessionID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeInternal, "Could not create token")
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse(access, refresh))
+}
+
+// logout revokes the session the refresh token belongs to. Unknown tokens
+// are accepted silently so the call is idempotent.
+func logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required,max=200"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	_, err := db.ExecContext(c.Request.Context(), `
+		UPDATE sessions SET revoked_at = NOW(), revoked_reason = $2
+		WHERE revoked_at IS NULL
+		  AND id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)
+	`, hashToken(req.RefreshToken), revokedLogout)
+	if err != nil {
+		respondDBError(c, err, "Could not log out")
+		return
+	}
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Logged out"})
+}
+
+// revokeAccountSessions lets an admin end every session of an account
+func revokeAccountSessions(c *gin.Context) {
+	id, ok := adminAccountID(c)
+	if !ok {
+		return
+	}
+	res, err := db.ExecContext(c.Request.Context(), `
+		UPDATE sessions SET revoked_at = NOW(), revoked_reason = $2
+		WHERE account_id = $1 AND revoked_at IS NULL
+	`, id, revokedAdmin)
+	if err != nil {
+		respondDBError(c, err, "Could not revoke sessions")
+		return
+	}
+	n, _ := res.RowsAffected()
+	slog.InfoContext(c.Request.Context(), "sessions revoked by admin",
+		"admin_id", accountID(c), "account_id", id, "count", n)
+	audit(c, db, auditEvent{action: auditSessionsRevoked, targetType: auditTargetAccount, targetID: id,
+		details: map[string]string{"revoked": strconv.FormatInt(n, 10)}})
+	c.JSON(http.StatusOK, models.RevokeSessionsResponse{Message: "Sessions revoked", Revoked: int(n)})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamInterval is how often a live stream re-reads its auction. Every
+// instance reads the same rows and clock, so clients connected to
+// different instances see the same prices.
+const streamInterval = 2 * time.Second
+
+// streamItem follows an auction as server-sent events. An "item" event
+// carrying the auction and its bids is sent on connect and whenever either
+// changes, including each Dutch price drop. The stream ends once the
+// auction is over, or when the server shuts down so clients reconnect
+// elsewhere.
+func streamItem(c *gin.Context) {
+	ctx := c.Request.Context()
+	itemID := c.Param("itemId")
+	detail, err := loadItemDetail(ctx, itemID)
+	if err == sql.ErrNoRows || (err == nil && detail.Item.Status == "draft") {
+		respondError(c, http.StatusNotFound, codeNotFound, "Item not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not fetch item")
+		return
+	}
+
+	streamUpdates(c, "item", detail, over(detail.Item.Status), func() (any, bool, error) {
+		d, err := loadItemDetail(ctx, itemID)
+		return d, over(d.Item.Status), err
+	})
+}
+
+// streamUpdates writes value as a server-sent event of the given name, then
+// re-reads it with reload every streamInterval and sends it again whenever
+// it changes. It stops once done, when the client goes away, or when the
+// server shuts down so clients reconnect elsewhere.
+func streamUpdates(c *gin.Context, event string, value any, done bool, reload func() (any, bool, error)) {
+	ctx := c.Request.Context()
+	subscribers := streamSubscribers.WithLabelValues(event)
+	subscribers.Inc()
+	defer subscribers.Dec()
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+	var last []byte
+	c.Stream(func(w io.Writer) bool {
+		payload, err := json.Marshal(value)
+		if err != nil {
+			slog.ErrorContext(ctx, "encoding stream event failed", "error", err)
+			return false
+		}
+		if !bytes.Equal(payload, last) {
+			c.SSEvent(event, json.RawMessage(payload))
+			last = payload
+		}
+		if done {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		if shuttingDown.Load() {
+			return false
+		}
+		if value, done, err = reload(); err != nil {
+			slog.ErrorContext(ctx, "refreshing stream failed", "event", event, "error", err)
+			return false
+		}
+		return true
+	})
+}
+
+// loadItemDetail reads an auction together with its bids and, for a lot,
+// the items it contains
+func loadItemDetail(ctx context.Context, itemID string) (models.ItemDetail, error) {
+	item, err := loadItem(ctx, itemID)
+	if err != nil {
+		return models.ItemDetail{}, err
+	}
+	detail := models.ItemDetail{Item: item}
+	if detail.Bids, err = revealedBids(ctx, item); err != nil {
+		return detail, err
+	}
+	if item.LotSize > 0 {
+		detail.LotItems, err = lotItems(ctx, db, item.ID)
+	}
+	return detail, err
+}
+
+// over reports whether an auction in the given public status is finished
+func over(status string) bool {
+	return status == "ended" || status == "cancelled"
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auction-system/config"
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Throttle scopes: failures are counted per login identifier and per
+// client IP so neither guessing one account nor spraying many works
+const (
+	scopeAccount = "account"
+	scopeIP      = "ip"
+)
+
+// loginLimits holds the brute-force settings; set by setupRouter
+var loginLimits = config.NewLoginThrottle()
+
+// dummyHash is compared against when an account does not exist, so a
+// login for an unknown email costs the same bcrypt work as a real one
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// throttleKey identifies one failure counter
+type throttleKey struct {
+	scope string
+	key   string
+}
+
+// maxFailures is the number of failures after which the key is locked
+func (k throttleKey) maxFailures() int {
+	if k.scope == scopeIP {
+		return loginLimits.IPMaxFailures
+	}
+	return loginLimits.MaxFailures
+}
+
+// accountKey groups attempts on the same email or username whether or not
+// the account exists, so lockouts do not reveal which ones do
+func accountKey(identifier string) throttleKey {
+	return throttleKey{scopeAccount, "login:" + strings.ToLower(strings.TrimSpace(identifier))}
+}
+
+// loginAttempt tracks one call to a login handler
+type loginAttempt struct {
+	c          *gin.Context
+	identifier string
+	account    throttleKey
+	reserved   []throttleReservation
+}
+
+// beginLogin rejects the attempt with 429 while either the identifier or
+// the client IP is backing off or locked, and otherwise reserves it
+// against the identifier. It reports whether the handler should go on to
+// check the password.
+func beginLogin(c *gin.Context, identifier string) (*loginAttempt, bool) {
+	a := &loginAttempt{
+		c:          c,
+		identifier: identifier,
+		account:    accountKey(identifier),
+	}
+	reserved, ok := reserveAttempt(c, a.account, throttleKey{scopeIP, c.ClientIP()})
+	if !ok {
+		return nil, false
+	}
+	a.reserved = reserved
+	return a, true
+}
+
+// throttleReservation is an attempt checked against a key before its
+// outcome is known. Unless the key is an IP, it is already counted as a
+// failure.
+type throttleReservation struct {
+	key         throttleKey
+	failures    int
+	lockedUntil time.Time
+	counted     bool
+}
+
+// countsUpFront reports whether attempts are counted as failures before
+// they are checked. IP keys are only counted once an attempt has failed:
+// reserving them would turn away every other login from a shared address
+// while one password is being checked.
+func (k throttleKey) countsUpFront() bool {
+	return k.scope != scopeIP
+}
+
+// reserveAttempt counts an attempt as a failure against every key that
+// counts up front, with the backoff that failure brings, unless one of
+// the keys is already backing off or locked; then it writes a 429 and
+// reports false. The counter rows stay locked from the check to the
+// update, so parallel guesses at one account cannot all slip past before
+// any failure is recorded: each waits for the one before and then finds
+// the key backing off. A successful attempt clears its reservations.
+func reserveAttempt(c *gin.Context, keys ...throttleKey) ([]throttleReservation, bool) {
+	ctx := c.Request.Context()
+	reserved, blocked, err := reserveKeys(ctx, time.Now(), keys)
+	if err != nil {
+		// Fail open: an outage of the throttle table must not lock
+		// everyone out, and bcrypt still rate-limits guessing
+		slog.ErrorContext(ctx, "login throttle reservation failed", "error", err)
+		return nil, true
+	}
+	if blocked == nil {
+		return reserved, true
+	}
+	loginThrottledTotal.WithLabelValues(blocked.key.scope).Inc()
+	c.Header("Retry-After", strconv.Itoa(int(time.Until(blocked.lockedUntil).Seconds())+1))
+	if blocked.key.scope == scopeAccount && blocked.failures >= blocked.key.maxFailures() {
+		respondError(c, http.StatusTooManyRequests, codeAccountLocked, "Too many failed logins; account temporarily locked")
+	} else {
+		respondError(c, http.StatusTooManyRequests, codeTooManyAttempts, "Too many login attempts; try again later")
+	}
+	return nil, false
+}
+
+// reserveKeys does the work of reserveAttempt in one transaction. Keys are
+// always locked in the order given, so concurrent logins cannot deadlock.
+// blocked is the key's current state when one is backing off or locked.
+func reserveKeys(ctx context.Context, now time.Time, keys []throttleKey) (reserved []throttleReservation, blocked *throttleReservation, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	for _, k := range keys {
+		r, lastFailure, err := lockCounter(ctx, tx, k, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.lockedUntil.After(now) {
+			return nil, &r, nil
+		}
+		if k.countsUpFront() {
+			if err := countFailure(ctx, tx, &r, lastFailure, now); err != nil {
+				return nil, nil, err
+			}
+		}
+		reserved = append(reserved, r)
+	}
+	return reserved, nil, tx.Commit()
+}
+
+// lockCounter takes the row lock on a key's counter and returns it. The
+// no-op update locks an existing counter; a new one starts at zero.
+func lockCounter(ctx context.Context, tx *sql.Tx, k throttleKey, now time.Time) (throttleReservation, time.Time, error) {
+	r := throttleReservation{key: k}
+	var lastFailure time.Time
+	var lockedUntil sql.NullTime
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO login_throttle (scope, key, failures, last_failure_at)
+		VALUES ($1, $2, 0, $3)
+		ON CONFLICT (scope, key) DO UPDATE SET scope = EXCLUDED.scope
+		RETURNING failures, last_failure_at, locked_until
+	`, k.scope, k.key, now).Scan(&r.failures, &lastFailure, &lockedUntil)
+	r.lockedUntil = lockedUntil.Time
+	return r, lastFailure, err
+}
+
+// countFailure adds a failure to a locked counter and backs the key off
+// accordingly. Failures older than the window no longer count.
+func countFailure(ctx context.Context, tx *sql.Tx, r *throttleReservation, lastFailure, now time.Time) error {
+	if lastFailure.Before(now.Add(-loginLimits.Window)) {
+		r.failures = 0
+	}
+	r.failures++
+	r.lockedUntil = now.Add(loginDelay(r.failures, r.key.maxFailures()))
+	r.counted = true
+	_, err := tx.ExecContext(ctx,
+		"UPDATE login_throttle SET failures = $3, last_failure_at = $4, locked_until = $5 WHERE scope = $1 AND key = $2",
+		r.key.scope, r.key.key, r.failures, now, r.lockedUntil)
+	return err
+}
+
+// failed notes a reserved attempt that turned out wrong, counting it now
+// if the key did not count it up front, and reports a key reaching its
+// lockout
+func (r throttleReservation) failed(ctx context.Context) {
+	if !r.counted {
+		if err := r.recordFailure(ctx); err != nil {
+			slog.ErrorContext(ctx, "recording login failure failed", "scope", r.key.scope, "error", err)
+			return
+		}
+	}
+	if r.failures == r.key.maxFailures() {
+		slog.WarnContext(ctx, "login locked out", "scope", r.key.scope, "failures", r.failures)
+	}
+}
+
+// recordFailure counts a failure against the reservation's key in its own
+// transaction
+func (r *throttleReservation) recordFailure(ctx context.Context) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := time.Now()
+	locked, lastFailure, err := lockCounter(ctx, tx, r.key, now)
+	if err != nil {
+		return err
+	}
+	if err := countFailure(ctx, tx, &locked, lastFailure, now); err != nil {
+		return err
+	}
+	*r = locked
+	return tx.Commit()
+}
+
+// passwordMatches compares a password with a bcrypt hash. An empty hash
+// (unknown account) is checked against dummyHash and never matches.
+func passwordMatches(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// fail counts the failure against the client IP, the identifier having
+// counted it in beginLogin, records it in the audit log and writes the 401. id is the matching
+// account, or 0 if there is none.
+func (a *loginAttempt) fail(id int) {
+	ctx := a.c.Request.Context()
+	loginFailuresTotal.WithLabelValues(factorPassword).Inc()
+	for _, r := range a.reserved {
+		r.failed(ctx)
+	}
+	ev := auditEvent{action: auditLoginFailed, targetType: auditTargetAccount,
+		details: map[string]string{"login": a.identifier, "factor": factorPassword}}
+	if id != 0 {
+		ev.targetID = id
+	}
+	audit(a.c, db, ev)
+	respondError(a.c, http.StatusUnauthorized, codeInvalidLogin, "Invalid credentials")
+}
+
+// succeed clears the identifier's failures. The IP counter is left alone,
+// so one valid account cannot be used to reset it between guesses.
+func (a *loginAttempt) succeed() {
+	clearLoginFailures(a.c.Request.Context(), a.account)
+}
+
+// clearLoginFailures forgets a failure counter
+func clearLoginFailures(ctx context.Context, k throttleKey) {
+	_, err := db.ExecContext(ctx, "DELETE FROM login_throttle WHERE scope = $1 AND key = $2", k.scope, k.key)
+	if err != nil {
+		slog.ErrorContext(ctx, "clearing login failures failed", "error", err)
+	}
+}
+
+// loginDelay is the wait imposed after the given number of failures:
+// exponential backoff below the threshold, never longer than the first
+// lockout, then an exponentially growing lockout, capped at MaxLockout.
+// A key with a high threshold, such as a shared IP, so backs off no
+// harder than an account until it actually reaches its threshold.
+func loginDelay(failures, maxFailures int) time.Duration {
+	base, exp, limit := loginLimits.Backoff, failures-1, loginLimits.Lockout
+	if failures >= maxFailures {
+		base, exp, limit = loginLimits.Lockout, failures-maxFailures, loginLimits.MaxLockout
+	}
+	limit = min(limit, loginLimits.MaxLockout)
+	delay := base
+	for i := 0; i < exp && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// unlockAccount lets an admin clear an account's failed logins and lockout
+func unlockAccount(c *gin.Context) {
+	id, ok := adminAccountID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var email, username string
+	err := db.QueryRowContext(ctx,
+		"SELECT COALESCE(email, ''), COALESCE(username, '') FROM accounts WHERE id = $1", id).Scan(&email, &username)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, codeNotFound, "Account not found")
+		return
+	}
+	if err != nil {
+		respondDBError(c, err, "Could not unlock account")
+		return
+	}
+	if err := clearAccountLockout(ctx, db, email, username); err != nil {
+		respondDBError(c, err, "Could not unlock account")
+		return
+	}
+	slog.InfoContext(ctx, "account unlocked by admin", "admin_id", accountID(c), "account_id", id)
+	audit(c, db, auditEvent{action: auditAccountUnlocked, targetType: auditTargetAccount, targetID: id})
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "Account unlocked"})
+}
+
+// clearAccountLockout forgets the login failures of each identifier an
+// account signs in with; empty ones are skipped
+func clearAccountLockout(ctx context.Context, ex execer, identifiers ...string) error {
+	for _, identifier := range identifiers {
+		if identifier == "" {
+			continue
+		}
+		k := accountKey(identifier)
+		if _, err := ex.ExecContext(ctx, "DELETE FROM login_throttle WHERE scope = $1 AND key = $2", k.scope, k.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
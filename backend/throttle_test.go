@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"auction-system/config"
+)
+
+func TestLoginDelay(t *testing.T) {
+	saved := loginLimits
+	t.Cleanup(func() { loginLimits = saved })
+	loginLimits = &config.LoginThrottle{
+		MaxFailures:   5,
+		IPMaxFailures: 20,
+		Backoff:       time.Second,
+		Lockout:       15 * time.Minute,
+		MaxLockout:    2 * time.Hour,
+		Window:        time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		max      int
+		want     time.Duration
+	}{
+		{"first failure backs off", 1, 5, time.Second},
+		{"backoff doubles", 2, 5, 2 * time.Second},
+		{"backoff keeps doubling", 4, 5, 8 * time.Second},
+		{"threshold locks", 5, 5, 15 * time.Minute},
+		{"lockout doubles", 6, 5, 30 * time.Minute},
+		{"lockout keeps doubling", 7, 5, time.Hour},
+		{"lockout is capped", 8, 5, 2 * time.Hour},
+		{"cap holds far past the threshold", 500, 5, 2 * time.Hour},
+		{"ip threshold is separate", 6, 20, 32 * time.Second},
+		{"backoff never passes the first lockout", 11, 20, 15 * time.Minute},
+		{"backoff holds at the first lockout up to the threshold", 19, 20, 15 * time.Minute},
+		{"ip threshold locks", 20, 20, 15 * time.Minute},
+		{"ip lockout doubles", 21, 20, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loginDelay(tt.failures, tt.max); got != tt.want {
+				t.Errorf("loginDelay(%d, %d) = %s, want %s", tt.failures, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginDelayMaxLockoutBelowLockout(t *testing.T) {
+	// A MaxLockout set below Lockout caps the backoff as well
+	saved := loginLimits
+	t.Cleanup(func() { loginLimits = saved })
+	loginLimits = &config.LoginThrottle{Backoff: time.Minute, Lockout: time.Hour, MaxLockout: 10 * time.Minute}
+
+	if got := loginDelay(19, 20); got != 10*time.Minute {
+		t.Errorf("loginDelay(19, 20) = %s, want the 10m cap", got)
+	}
+	if got := loginDelay(20, 20); got != 10*time.Minute {
+		t.Errorf("loginDelay(20, 20) = %s, want the 10m cap", got)
+	}
+}
+
+func TestThrottleKeyCountsUpFront(t *testing.T) {
+	if !accountKey("bob@example.com").countsUpFront() {
+		t.Error("account keys must be reserved before the password is checked")
+	}
+	if !mfaThrottleKey(7).countsUpFront() {
+		t.Error("second-factor keys must be reserved before the code is checked")
+	}
+	if (throttleKey{scopeIP, "192.0.2.1"}).countsUpFront() {
+		t.Error("ip keys must only count failed attempts")
+	}
+}
+
+func TestThrottleKeyMaxFailures(t *testing.T) {
+	saved := loginLimits
+	t.Cleanup(func() { loginLimits = saved })
+	loginLimits = &config.LoginThrottle{MaxFailures: 5, IPMaxFailures: 20}
+
+	if got := accountKey("Bob@Example.com ").maxFailures(); got != 5 {
+		t.Errorf("account key maxFailures = %d, want 5", got)
+	}
+	if got := (throttleKey{scopeIP, "192.0.2.1"}).maxFailures(); got != 20 {
+		t.Errorf("ip key maxFailures = %d, want 20", got)
+	}
+	if got := accountKey("Bob@Example.com ").key; got != "login:bob@example.com" {
+		t.Errorf("accountKey = %q, want it trimmed and lower-cased", got)
+	}
+}
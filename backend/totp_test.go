@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode at T=%d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfc6238Secret)
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	codeAt := func(s int64) string { return totpCode(rfc6238Secret, s) }
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", secret, codeAt(step), 0, step, true},
+		{"previous step within skew", secret, codeAt(step - 1), 0, step - 1, true},
+		{"next step within skew", secret, codeAt(step + 1), 0, step + 1, true},
+		{"two steps back is outside skew", secret, codeAt(step - 2), 0, 0, false},
+		{"two steps ahead is outside skew", secret, codeAt(step + 2), 0, 0, false},
+		{"lower-case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", codeAt(step), 0, step, true},
+		{"replayed step is refused", secret, codeAt(step), step, 0, false},
+		{"earlier step after a later one is refused", secret, codeAt(step - 1), step, 0, false},
+		{"step after the last used is accepted", secret, codeAt(step + 1), step, step + 1, true},
+		{"wrong code", secret, "000000", 0, 0, false},
+		{"short code", secret, codeAt(step)[:5], 0, 0, false},
+		{"invalid secret", "not base32!", codeAt(step), 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := verifyTOTP(tt.secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("verifyTOTP = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret := newTOTPSecret()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("newTOTPSecret() = %q, want 20 bytes of base32 (err %v)", secret, err)
+	}
+	if newTOTPSecret() == secret {
+		t.Error("newTOTPSecret returned the same secret twice")
+	}
+}
+
+func TestOTPAuthURI(t *testing.T) {
+	u, err := url.Parse(otpauthURI("Auction House", "bob@example.com", "ABC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Auction House:bob@example.com" {
+		t.Errorf("unexpected URI %s", u)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{"secret": "ABC", "issuer": "Auction House", "algorithm": "SHA1", "digits": "6", "period": "30"} {
+		if q.Get(key) != want {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), want)
+		}
+	}
+}
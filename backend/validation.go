@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"auction-system/config"
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Limits shared by the validation tags and the OpenAPI document
+const (
+	maxPrice          = 99999999.99 // DECIMAL(10,2)
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+)
+
+// auctionRules are the listing limits in force; set by registerValidators
+var auctionRules = config.NewAuctionRules()
+
+var registerOnce sync.Once
+
+// registerValidators installs the custom validation tags used by request
+// structs and makes field errors report JSON field names
+func registerValidators(rules *config.AuctionRules) {
+	auctionRules = rules
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+		v.RegisterValidation("notblank", validateNotBlank)
+		v.RegisterValidation("password", validatePassword)
+		v.RegisterValidation("finite", validateFinite)
+		v.RegisterValidation("auction_start", validateAuctionStart)
+		v.RegisterValidation("auction_end", validateAuctionEnd)
+	})
+}
+
+// validateNotBlank rejects strings made only of whitespace
+func validateNotBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// validatePassword enforces the password policy: 8 to 72 bytes with at
+// least one letter and one digit
+func validatePassword(fl validator.FieldLevel) bool {
+	pw := fl.Field().String()
+	if len(pw) < minPasswordLength || len(pw) > maxPasswordLength {
+		return false
+	}
+	var letter, digit bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return letter && digit
+}
+
+// validateFinite rejects NaN and infinite amounts
+func validateFinite(fl validator.FieldLevel) bool {
+	f := fl.Field().Float()
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// validateAuctionStart requires a start time in the future but no further
+// ahead than the configured lead time
+func validateAuctionStart(fl validator.FieldLevel) bool {
+	start, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	return start.After(now) && !start.After(now.Add(auctionRules.MaxLeadTime))
+}
+
+// validateAuctionEnd requires an end time inside the configured window,
+// measured from the request's StartTime when it sets one and from now
+// otherwise
+func validateAuctionEnd(fl validator.FieldLevel) bool {
+	end, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	from := time.Now()
+	if start, ok := requestStart(fl.Parent()); ok && start.After(from) {
+		from = start
+	}
+	return !end.Before(from.Add(auctionRules.MinDuration)) && !end.After(from.Add(auctionRules.MaxDuration))
+}
+
+// requestStart reads the optional StartTime field of a request struct
+func requestStart(req reflect.Value) (time.Time, bool) {
+	if req.Kind() != reflect.Struct {
+		return time.Time{}, false
+	}
+	f := req.FieldByName("StartTime")
+	if !f.IsValid() || f.Kind() != reflect.Pointer || f.IsNil() {
+		return time.Time{}, false
+	}
+	start, ok := f.Elem().Interface().(time.Time)
+	return start, ok
+}
+
+// bindJSON decodes and validates the request body into req. On failure it
+// writes a validation error listing every offending field and returns false.
+func bindJSON(c *gin.Context, req any) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		details := make([]models.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			details = append(details, models.FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+		}
+		respondError(c, http.StatusBadRequest, codeValidationFailed, "Invalid input", details...)
+		return false
+	}
+	respondError(c, http.StatusBadRequest, codeInvalidRequest, "Invalid input",
+		models.FieldError{Field: "body", Message: err.Error()})
+	return false
+}
+
+// structProblems validates v, a request struct loaded from storage, with
+// its binding tags and reports each failure as bindJSON would
+func structProblems(v any) []models.FieldError {
+	var verrs validator.ValidationErrors
+	if !errors.As(binding.Validator.ValidateStruct(v), &verrs) {
+		return nil
+	}
+	details := make([]models.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		details = append(details, models.FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+	}
+	return details
+}
+
+// fieldPath is the JSON path of a failed field, such as dutch.price_step
+// or lot_items[3].name: its namespace without the request struct's name
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// fieldMessage describes a failed validation tag in plain words
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "notblank":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "password":
+		return fmt.Sprintf("must be %d to %d characters and contain a letter and a digit", minPasswordLength, maxPasswordLength)
+	case "finite":
+		return "must be a finite number"
+	case "auction_start":
+		return fmt.Sprintf("must be in the future and at most %s from now", auctionRules.MaxLeadTime)
+	case "auction_end":
+		return fmt.Sprintf("must be between %s and %s after the start", auctionRules.MinDuration, auctionRules.MaxDuration)
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + fe.Param() + " characters"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "must be at most " + fe.Param() + " characters"
+		}
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	}
+	return "is invalid"
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-system/config"
+	"auction-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useAuctionRules registers the custom validators with fixed listing limits
+func useAuctionRules(t *testing.T) {
+	t.Helper()
+	saved := auctionRules
+	t.Cleanup(func() { auctionRules = saved })
+	registerValidators(&config.AuctionRules{MinDuration: time.Hour, MaxDuration: 24 * time.Hour, MaxLeadTime: 7 * 24 * time.Hour})
+}
+
+// validatorProbe exercises each custom tag the way request structs use them
+type validatorProbe struct {
+	Title     string     `json:"title" binding:"notblank"`
+	Password  string     `json:"password" binding:"password"`
+	Amount    float64    `json:"amount" binding:"finite"`
+	StartTime *time.Time `json:"start_time" binding:"omitempty,auction_start"`
+	EndTime   time.Time  `json:"end_time" binding:"auction_end"`
+}
+
+func TestCustomValidators(t *testing.T) {
+	useAuctionRules(t)
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		ts := now.Add(d)
+		return &ts
+	}
+
+	tests := []struct {
+		name  string
+		edit  func(*validatorProbe)
+		field string // the one field expected to fail, if any
+	}{
+		{"valid", func(*validatorProbe) {}, ""},
+		{"blank title", func(p *validatorProbe) { p.Title = " \t\n" }, "title"},
+		{"empty title", func(p *validatorProbe) { p.Title = "" }, "title"},
+		{"password too short", func(p *validatorProbe) { p.Password = "abc123" }, "password"},
+		{"password without a digit", func(p *validatorProbe) { p.Password = "abcdefgh" }, "password"},
+		{"password without a letter", func(p *validatorProbe) { p.Password = "12345678" }, "password"},
+		{"password over bcrypt's limit", func(p *validatorProbe) { p.Password = strings.Repeat("a1", 37) }, "password"},
+		{"password at bcrypt's limit", func(p *validatorProbe) { p.Password = strings.Repeat("a1", 36) }, ""},
+		{"password with non-ASCII letters", func(p *validatorProbe) { p.Password = "pässwört1" }, ""},
+		{"NaN amount", func(p *validatorProbe) { p.Amount = math.NaN() }, "amount"},
+		{"infinite amount", func(p *validatorProbe) { p.Amount = math.Inf(1) }, "amount"},
+		{"negative infinite amount", func(p *validatorProbe) { p.Amount = math.Inf(-1) }, "amount"},
+		{"start in the past", func(p *validatorProbe) { p.StartTime = at(-time.Minute) }, "start_time"},
+		{"start beyond the lead time", func(p *validatorProbe) {
+			p.StartTime, p.EndTime = at(8*24*time.Hour), now.Add(8*24*time.Hour+2*time.Hour)
+		}, "start_time"},
+		{"end measured from the start", func(p *validatorProbe) {
+			p.StartTime, p.EndTime = at(48*time.Hour), now.Add(50*time.Hour)
+		}, ""},
+		{"end too soon after now", func(p *validatorProbe) { p.EndTime = now.Add(30 * time.Minute) }, "end_time"},
+		{"end too soon after the start", func(p *validatorProbe) {
+			p.StartTime, p.EndTime = at(10*time.Hour), now.Add(10*time.Hour+30*time.Minute)
+		}, "end_time"},
+		{"end too long after the start", func(p *validatorProbe) {
+			p.StartTime, p.EndTime = at(time.Hour), now.Add(26*time.Hour)
+		}, "end_time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validatorProbe{Title: "Clock", Password: "hunter22", Amount: 10, EndTime: now.Add(2 * time.Hour)}
+			tt.edit(&p)
+			var fields []string
+			for _, fe := range structProblems(&p) {
+				fields = append(fields, fe.Field)
+			}
+			var want []string
+			if tt.field != "" {
+				want = []string{tt.field}
+			}
+			if !reflect.DeepEqual(fields, want) {
+				t.Errorf("failed fields = %v, want %v", fields, want)
+			}
+		})
+	}
+}
+
+// bindProbe is a request body with the common built-in tags and nested
+// terms, as listing requests have
+type bindProbe struct {
+	Name   string  `json:"name" binding:"required,min=3"`
+	Amount float64 `json:"amount" binding:"gt=0"`
+	Note   string  `json:"note" binding:"max=5"`
+	Terms  *struct {
+		Step float64 `json:"step" binding:"gt=0"`
+	} `json:"terms"`
+	Items []struct {
+		Name string `json:"name" binding:"required"`
+	} `json:"items" binding:"dive"`
+}
+
+func TestBindJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useAuctionRules(t)
+
+	tests := []struct {
+		name    string
+		body    string
+		code    string
+		details []models.FieldError
+	}{
+		{"valid body", `{"name":"Clock","amount":5}`, "", nil},
+		{"missing field", `{"amount":5}`, codeValidationFailed, []models.FieldError{
+			{Field: "name", Message: "is required"},
+		}},
+		{"every failure is listed", `{"name":"ab","amount":0,"note":"too long"}`, codeValidationFailed, []models.FieldError{
+			{Field: "name", Message: "must be at least 3 characters"},
+			{Field: "amount", Message: "must be greater than 0"},
+			{Field: "note", Message: "must be at most 5 characters"},
+		}},
+		{"nested failures keep their path", `{"name":"Clock","amount":5,"terms":{"step":0},"items":[{"name":"a"},{}]}`, codeValidationFailed, []models.FieldError{
+			{Field: "terms.step", Message: "must be greater than 0"},
+			{Field: "items[1].name", Message: "is required"},
+		}},
+		{"malformed JSON", `{"name":`, codeInvalidRequest, nil},
+		{"wrong type", `{"name":"Clock","amount":"five"}`, codeInvalidRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var req bindProbe
+			ok := bindJSON(c, &req)
+			if tt.code == "" {
+				if !ok {
+					t.Fatalf("bindJSON refused a valid body: %s", w.Body)
+				}
+				return
+			}
+			if ok || w.Code != http.StatusBadRequest {
+				t.Fatalf("bindJSON = %v with status %d, want false with 400", ok, w.Code)
+			}
+			var resp models.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != tt.code {
+				t.Errorf("code = %q, want %q", resp.Error.Code, tt.code)
+			}
+			if tt.code == codeInvalidRequest {
+				if len(resp.Error.Details) != 1 || resp.Error.Details[0].Field != "body" {
+					t.Errorf("details = %v, want one entry for the body", resp.Error.Details)
+				}
+				return
+			}
+			if !reflect.DeepEqual(resp.Error.Details, tt.details) {
+				t.Errorf("details = %v, want %v", resp.Error.Details, tt.details)
+			}
+		})
+	}
+}
+
+func TestStructProblemsStoredTerms(t *testing.T) {
+	useAuctionRules(t)
+	tests := []struct {
+		name  string
+		terms listingTerms
+		want  string
+	}{
+		{"dutch", listingTerms{format: formatDutch, interval: sql.NullInt64{Int64: 60, Valid: true}}, "dutch.price_step"},
+		{"sealed", listingTerms{format: formatSealed}, "sealed.pricing"},
+		{"multi_unit", listingTerms{format: formatMultiUnit, clearing: sql.NullString{String: clearingUniform, Valid: true}}, "multi_unit.quantity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.terms.problems(100)
+			if len(problems) == 0 || problems[0].Field != tt.want {
+				t.Errorf("problems = %v, want the first under %s", problems, tt.want)
+			}
+		})
+	}
+}
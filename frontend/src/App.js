@@ -6,6 +6,8 @@ import Register from './components/Register';
 import SellerLogin from './components/SellerLogin';
 import SellerRegister from './components/SellerRegister';
 import AdminLogin from './components/AdminLogin';
+import ResetPassword from './components/ResetPassword';
+import VerifyEmail from './components/VerifyEmail';
 import UserHome from './components/UserHome';
 import SellerDashboard from './components/SellerDashboard';
 import AdminDashboard from './components/AdminDashboard';
@@ -27,6 +29,8 @@ function App() {
               <Route path="/seller-login" element={<SellerLogin />} />
               <Route path="/seller-register" element={<SellerRegister />} />
               <Route path="/admin-login" element={<AdminLogin />} />
+              <Route path="/reset-password" element={<ResetPassword />} />
+              <Route path="/verify-email" element={<VerifyEmail />} />
               <Route path="/dashboard" element={<UserHome />} />
               <Route path="/seller-dashboard" element={<SellerDashboard />} />
               <Route 
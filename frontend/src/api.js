@@ -7,12 +7,16 @@ const getHeaders = (token) => ({
   'Accept': 'application/json'
 });
 
-// Auth APIs
-export const login = async (credentials) => {
-  const response = await fetch(`${API_BASE_URL}/users/login`, {
+// Extract the human-readable message from an API error envelope
+export const errorMessage = (data, fallback) => data?.error?.message || fallback;
+
+// Auth APIs. Every account signs in through /auth/login with its email,
+// or username for admins; the response lists the roles it holds.
+export const login = async (identifier, password) => {
+  const response = await fetch(`${API_BASE_URL}/auth/login`, {
     method: 'POST',
     headers: getHeaders(),
-    body: JSON.stringify(credentials)
+    body: JSON.stringify({ login: identifier, password })
   });
   return response.json();
 };
@@ -28,23 +32,12 @@ export const register = async (userData) => {
 
 export const sellerLogin = async (credentials) => {
   try {
-    const response = await fetch(`${API_BASE_URL}/sellers/login`, {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json'
-      },
-      body: JSON.stringify({
-        email: credentials.email,
-        password: credentials.password
-      })
-    });
-    
-    const data = await response.json();
-    
-    if (!response.ok) {
-      throw new Error(data.error || 'Login failed');
+    const data = await login(credentials.email, credentials.password);
+
+    if (data.error) {
+      throw new Error(errorMessage(data, 'Login failed'));
     }
-    
+
     return data;
   } catch (error) {
     console.error('Login error details:', error);
@@ -72,7 +65,7 @@ export const sellerRegister = async (sellerData) => {
     console.log('Server response:', data);
     
     if (!response.ok) {
-      throw new Error(data.error || 'Registration failed');
+      throw new Error(errorMessage(data, 'Registration failed'));
     }
 
     // Validate response data
@@ -91,15 +84,60 @@ export const sellerRegister = async (sellerData) => {
   }
 };
 
-export const adminLogin = async (credentials) => {
-  const response = await fetch(`${API_BASE_URL}/admin/login`, {
+// Exchange a refresh token for a new access/refresh token pair
+export const refreshSession = async (refreshToken) => {
+  const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+    method: 'POST',
+    headers: getHeaders(),
+    body: JSON.stringify({ refresh_token: refreshToken })
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(errorMessage(data, 'Session expired'));
+  }
+  return data;
+};
+
+// Revoke the server-side session behind a refresh token
+export const logout = async (refreshToken) => {
+  const response = await fetch(`${API_BASE_URL}/auth/logout`, {
     method: 'POST',
     headers: getHeaders(),
-    body: JSON.stringify(credentials)
+    body: JSON.stringify({ refresh_token: refreshToken })
   });
   return response.json();
 };
 
+// Account recovery APIs
+const postJSON = async (path, body) => {
+  const response = await fetch(`${API_BASE_URL}${path}`, {
+    method: 'POST',
+    headers: getHeaders(),
+    body: JSON.stringify(body)
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(errorMessage(data, 'Request failed'));
+  }
+  return data;
+};
+
+export const requestPasswordReset = (email, role = 'user') =>
+  postJSON('/auth/password-reset', { email, role });
+
+export const confirmPasswordReset = (token, password) =>
+  postJSON('/auth/password-reset/confirm', { token, password });
+
+export const verifyEmail = (token) => postJSON('/auth/verify-email', { token });
+
+// Second step of a login that returned mfa_required
+export const mfaEnroll = (mfaToken) => postJSON('/auth/mfa/enroll', { mfa_token: mfaToken });
+
+export const mfaVerify = (mfaToken, code) =>
+  postJSON('/auth/mfa/verify', { mfa_token: mfaToken, code });
+
+export const adminLogin = (credentials) => login(credentials.username, credentials.password);
+
 // Auction APIs
 export const createAuction = async (auctionData, token) => {
   const response = await fetch(`${API_BASE_URL}/auctions`, {
@@ -118,6 +156,14 @@ export const getActiveAuctions = async () => {
   return response.json();
 };
 
+export const getUpcomingAuctions = async () => {
+  const response = await fetch(`${API_BASE_URL}/auctions/upcoming`, {
+    method: 'GET',
+    headers: getHeaders()
+  });
+  return response.json();
+};
+
 export const getPastAuctions = async () => {
   const response = await fetch(`${API_BASE_URL}/auctions/past`, {
     method: 'GET',
@@ -134,6 +180,53 @@ export const getAuctionById = async (auctionId) => {
   return response.json();
 };
 
+// Drafts stay hidden until published with their start and end times
+export const createDraft = async (draftData, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/drafts`, {
+    method: 'POST',
+    headers: getHeaders(token),
+    body: JSON.stringify(draftData)
+  });
+  return response.json();
+};
+
+// Buyers post a request with ceiling_price and bid_decrement; sellers bid down
+export const createReverseAuction = async (requestData, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/reverse`, {
+    method: 'POST',
+    headers: getHeaders(token),
+    body: JSON.stringify(requestData)
+  });
+  return response.json();
+};
+
+export const publishAuction = async (auctionId, timing, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}/publish`, {
+    method: 'POST',
+    headers: getHeaders(token),
+    body: JSON.stringify(timing)
+  });
+  return response.json();
+};
+
+// Once bids exist only the description can change and the end time move later
+export const updateAuction = async (auctionId, changes, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}`, {
+    method: 'PUT',
+    headers: getHeaders(token),
+    body: JSON.stringify(changes)
+  });
+  return response.json();
+};
+
+export const getAuctionRevisions = async (auctionId) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}/revisions`, {
+    method: 'GET',
+    headers: getHeaders()
+  });
+  return response.json();
+};
+
 export const placeBid = async (auctionId, bidData, token) => {
   const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}/bid`, {
     method: 'POST',
@@ -143,14 +236,81 @@ export const placeBid = async (auctionId, bidData, token) => {
   return response.json();
 };
 
-export const cancelAuction = async (auctionId, token) => {
+// Follows an auction live; onItem receives the auction and its bids on
+// connect and after every change, including each Dutch price drop
+export const streamAuction = (auctionId, onItem) => {
+  const source = new EventSource(`${API_BASE_URL}/auctions/${auctionId}/stream`);
+  source.addEventListener('item', (event) => onItem(JSON.parse(event.data)));
+  return source;
+};
+
+// A reason is required once the auction has bids
+export const cancelAuction = async (auctionId, token, reason) => {
   const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}/cancel`, {
+    method: 'POST',
+    headers: getHeaders(token),
+    body: reason ? JSON.stringify({ reason }) : undefined
+  });
+  return response.json();
+};
+
+// Turns an unsold lot into one draft per item
+export const splitLot = async (auctionId, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}/split`, {
     method: 'POST',
     headers: getHeaders(token)
   });
   return response.json();
 };
 
+// Gathers drafts or unsold listings into a new draft lot
+export const mergeListings = async (itemIds, name, description, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/merge`, {
+    method: 'POST',
+    headers: getHeaders(token),
+    body: JSON.stringify({ item_ids: itemIds, name, description })
+  });
+  return response.json();
+};
+
+export const getLiveSessions = async () => {
+  const response = await fetch(`${API_BASE_URL}/live-sessions`);
+  return response.json();
+};
+
+export const getLiveSession = async (sessionId) => {
+  const response = await fetch(`${API_BASE_URL}/live-sessions/${sessionId}`);
+  return response.json();
+};
+
+// Follows a live session; onSession receives the session and its lots on
+// connect and after every bid and call
+export const streamLiveSession = (sessionId, onSession) => {
+  const source = new EventSource(`${API_BASE_URL}/live-sessions/${sessionId}/stream`);
+  source.addEventListener('session', (event) => onSession(JSON.parse(event.data)));
+  return source;
+};
+
+// Leaves the most the bidder will pay for a lot that has not opened yet
+export const placeAbsenteeBid = async (auctionId, maxAmount, token) => {
+  const response = await fetch(`${API_BASE_URL}/auctions/${auctionId}/absentee-bid`, {
+    method: 'PUT',
+    headers: getHeaders(token),
+    body: JSON.stringify({ max_amount: maxAmount })
+  });
+  return response.json();
+};
+
+// Makes an auctioneer's call: open, accept, going_once, going_twice or hammer
+export const callLot = async (sessionId, action, token, itemId) => {
+  const response = await fetch(`${API_BASE_URL}/live-sessions/${sessionId}/console`, {
+    method: 'POST',
+    headers: getHeaders(token),
+    body: JSON.stringify(itemId ? { action, item_id: itemId } : { action })
+  });
+  return response.json();
+};
+
 // User APIs
 export const getUserProfile = async (token) => {
   const response = await fetch(`${API_BASE_URL}/users/profile`, {
@@ -177,6 +337,25 @@ export const getSellerProfile = async (token) => {
   return response.json();
 };
 
+// Map an API auction onto the shape used by the dashboard components
+const toDashboardAuction = (auction) => ({
+  id: auction.id,
+  title: auction.name,
+  description: auction.description,
+  basePrice: auction.starting_price,
+  currentBid: auction.current_price,
+  status: auction.status,
+  endTime: auction.end_time,
+  sellerId: auction.seller_id,
+  sellerName: auction.seller,
+  bids: (auction.bids || []).map((bid) => ({
+    userId: bid.bidder_id,
+    userName: bid.bidder_name,
+    amount: bid.amount,
+    timestamp: bid.bid_time
+  }))
+});
+
 export const getSellerAuctions = async (sellerId, token) => {
   try {
     const response = await fetch(`${API_BASE_URL}/sellers/${sellerId}/auctions`, {
@@ -187,10 +366,10 @@ export const getSellerAuctions = async (sellerId, token) => {
     const data = await response.json();
 
     if (!response.ok) {
-      throw new Error(data.error || 'Failed to fetch seller auctions');
+      throw new Error(errorMessage(data, 'Failed to fetch seller auctions'));
     }
 
-    return data;
+    return Array.isArray(data) ? data.map(toDashboardAuction) : data;
   } catch (error) {
     console.error('Error fetching seller auctions:', error);
     throw error;
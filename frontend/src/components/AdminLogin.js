@@ -1,33 +1,54 @@
 import React, { useState } from 'react';
 import { useNavigate } from 'react-router-dom';
+import { adminLogin, logout, errorMessage } from '../api';
+import SecondFactor from './SecondFactor';
 import './Auth.css';
 
 function AdminLogin() {
   const navigate = useNavigate();
   const [formData, setFormData] = useState({
-    email: '',
+    username: '',
     password: ''
   });
   const [error, setError] = useState('');
+  const [challenge, setChallenge] = useState(null);
 
   const handleChange = (e) => {
     setFormData({ ...formData, [e.target.name]: e.target.value });
   };
 
-  const handleSubmit = (e) => {
+  const handleSubmit = async (e) => {
     e.preventDefault();
-    
-    // Check for hardcoded admin credentials
-    if (formData.email === 'admin@gmail.com' && formData.password === 'admin123') {
-      // Store admin authentication in localStorage
-      localStorage.setItem('adminAuth', 'true');
-      // Redirect to admin dashboard
-      navigate('/admin/dashboard');
-    } else {
-      setError('Invalid admin credentials');
+    setError('');
+
+    const data = await adminLogin(formData);
+    if (data.mfa_required) {
+      setChallenge(data);
+      return;
+    }
+    if (!data.token) {
+      setError(errorMessage(data, 'Invalid admin credentials'));
+      return;
+    }
+    if (!data.account.roles.includes('admin')) {
+      logout(data.refresh_token);
+      setError('This account is not an administrator');
+      return;
     }
+    completeLogin(data);
   };
 
+  const completeLogin = (data) => {
+    localStorage.setItem('adminToken', data.token);
+    localStorage.setItem('adminRefreshToken', data.refresh_token);
+    localStorage.setItem('adminAuth', 'true');
+    navigate('/admin/dashboard');
+  };
+
+  if (challenge) {
+    return <SecondFactor challenge={challenge} onComplete={completeLogin} />;
+  }
+
   return (
     <div className="auth-container">
       <div className="auth-box">
@@ -36,15 +57,15 @@ function AdminLogin() {
         {error && <p className="error-message">{error}</p>}
         <form onSubmit={handleSubmit} className="auth-form">
           <div className="form-group">
-            <label>Email:</label>
+            <label>Username:</label>
             <input 
-              type="email" 
-              name="email" 
-              value={formData.email} 
+              type="text" 
+              name="username" 
+              value={formData.username} 
               onChange={handleChange} 
               required 
               className="form-input"
-              placeholder="Enter admin email"
+              placeholder="Enter admin username"
             />
           </div>
           <div className="form-group">
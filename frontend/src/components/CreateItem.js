@@ -1,5 +1,6 @@
 import React, { useState } from 'react';
 import axios from 'axios';
+import { errorMessage } from '../api';
 import { useNavigate } from 'react-router-dom';
 import {
   Container,
@@ -19,6 +20,7 @@ function CreateItem() {
     name: '',
     description: '',
     starting_price: '',
+    start_time: '',
     end_time: ''
   });
   const [errors, setErrors] = useState({});
@@ -41,6 +43,7 @@ function CreateItem() {
 
     const newErrors = {};
     const now = new Date();
+    const startTime = formData.start_time ? new Date(formData.start_time) : now;
     const endTime = new Date(formData.end_time);
 
     if (!formData.name.trim()) {
@@ -56,6 +59,11 @@ function CreateItem() {
       newErrors.end_time = 'End time is required';
     } else if (endTime <= now) {
       newErrors.end_time = 'End time must be in the future';
+    } else if (endTime <= startTime) {
+      newErrors.end_time = 'End time must be after the start time';
+    }
+    if (formData.start_time && startTime <= now) {
+      newErrors.start_time = 'Start time must be in the future';
     }
 
     setErrors(newErrors);
@@ -67,12 +75,15 @@ function CreateItem() {
       const response = await axios.post('/api/items', {
         ...formData,
         starting_price: parseFloat(formData.starting_price),
+        // Leaving the start blank opens bidding straight away
+        start_time: formData.start_time ? startTime.toISOString() : undefined,
+        end_time: endTime.toISOString(),
       });
       setSuccess(response.data.message);
-      setFormData({ name: '', description: '', starting_price: '', end_time: '' });
+      setFormData({ name: '', description: '', starting_price: '', start_time: '', end_time: '' });
       setTimeout(() => navigate('/items'), 1500);
     } catch (err) {
-      setError(err.response?.data?.error || 'Failed to create item');
+      setError(errorMessage(err.response?.data, 'Failed to create item'));
     }
   };
 
@@ -145,6 +156,25 @@ function CreateItem() {
               />
             </Grid>
 
+            <Grid item xs={12} md={6}>
+              <TextField
+                fullWidth
+                label="Start Time (optional)"
+                name="start_time"
+                type="datetime-local"
+                value={formData.start_time}
+                onChange={handleChange}
+                error={!!errors.start_time}
+                helperText={errors.start_time || 'Leave blank to start immediately'}
+                InputLabelProps={{
+                  shrink: true,
+                }}
+                inputProps={{
+                  min: new Date().toISOString().slice(0, 16)
+                }}
+              />
+            </Grid>
+
             <Grid item xs={12} md={6}>
               <TextField
                 fullWidth
@@ -1,5 +1,5 @@
 import React, { useEffect, useState } from 'react';
-import API from '../api';
+import API, { errorMessage } from '../api';
 import { useParams } from 'react-router-dom';
 import {
   Container,
@@ -50,12 +50,12 @@ function ItemDetail() {
       setBids(res.data.bids);
       setItem(res.data.item);
     } catch (err) {
-      setError(err.response?.data?.error || 'Bid failed');
+      setError(errorMessage(err.response?.data, 'Bid failed'));
     }
   };
 
   const isAuctionEnded = item && new Date(item.end_time) < new Date();
-  const isUserSeller = item && token && item.seller_id === JSON.parse(atob(token.split('.')[1])).id;
+  const isUserSeller = item && token && item.seller_id === Number(JSON.parse(atob(token.split('.')[1])).sub);
 
   if (!item) return (
     <Container>
@@ -1,5 +1,6 @@
 import React, { useState } from 'react';
 import { Link, useNavigate } from 'react-router-dom';
+import { login, errorMessage } from '../api';
 import './Auth.css';
 
 function Login() {
@@ -22,22 +23,18 @@ function Login() {
     setIsLoading(true);
 
     try {
-      const response = await fetch('http://localhost:8080/api/users/login', {
-        method: 'POST',
-        headers: {
-          'Content-Type': 'application/json'
-        },
-        body: JSON.stringify(formData)
-      });
+      const data = await login(formData.email, formData.password);
 
-      const data = await response.json();
-      
-      if (!response.ok) {
-        throw new Error(data.error || 'Login failed');
+      if (data.error) {
+        throw new Error(errorMessage(data, 'Login failed'));
+      }
+      if (data.mfa_required) {
+        throw new Error('This account uses two-factor authentication; please sign in from the seller or admin login page');
       }
 
       // Store the token in localStorage
       localStorage.setItem('token', data.token);
+      localStorage.setItem('refreshToken', data.refresh_token);
       localStorage.setItem('userType', 'user');
       
       // Redirect to dashboard
@@ -98,6 +95,9 @@ function Login() {
         <p className="auth-switch">
           Don't have an account? <Link to="/register">Register here</Link>
         </p>
+        <p className="auth-switch">
+          <Link to="/reset-password">Forgot your password?</Link>
+        </p>
       </div>
     </div>
   );
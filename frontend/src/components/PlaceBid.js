@@ -1,5 +1,6 @@
 import React, { useState } from 'react';
 import axios from 'axios';
+import { errorMessage } from '../api';
 import { useParams } from 'react-router-dom';
 
 function PlaceBid() {
@@ -12,7 +13,7 @@ function PlaceBid() {
       const response = await axios.post(`/api/items/${itemId}/bid`, { bid_amount: parseFloat(bidAmount) });
       alert(response.data.message);
     } catch (error) {
-      alert(error.response?.data?.error || 'Failed to place bid');
+      alert(errorMessage(error.response?.data, 'Failed to place bid'));
     }
   };
 
@@ -1,5 +1,6 @@
 import React, { useState } from 'react';
 import { Link, useNavigate } from 'react-router-dom';
+import { errorMessage } from '../api';
 import './Auth.css';
 
 function Register() {
@@ -29,7 +30,7 @@ function Register() {
       const data = await response.json();
       
       if (!response.ok) {
-        throw new Error(data.error || 'Registration failed');
+        throw new Error(errorMessage(data, 'Registration failed'));
       }
 
       alert(data.message || 'Registration successful!');
@@ -0,0 +1,91 @@
+import React, { useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { requestPasswordReset, confirmPasswordReset } from '../api';
+import './Auth.css';
+
+// Without a token this asks for an email to send the reset link to; the
+// link in that email brings the user back here with ?token= to choose a
+// new password.
+function ResetPassword() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [email, setEmail] = useState('');
+  const [role, setRole] = useState('user');
+  const [password, setPassword] = useState('');
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const [isLoading, setIsLoading] = useState(false);
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    setMessage('');
+    setIsLoading(true);
+    try {
+      const data = token
+        ? await confirmPasswordReset(token, password)
+        : await requestPasswordReset(email, role);
+      setMessage(data.message);
+    } catch (err) {
+      setError(err.message);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <div className="auth-container">
+      <div className="auth-box">
+        <h2>{token ? 'Choose a new password' : 'Reset your password'}</h2>
+        {error && <div className="error-message">{error}</div>}
+        {message && <p className="welcome-message">{message}</p>}
+        <form onSubmit={handleSubmit} className="auth-form">
+          {token ? (
+            <div className="form-group">
+              <label>New password:</label>
+              <input
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                required
+                minLength={8}
+                maxLength={72}
+                className="form-input"
+                disabled={isLoading}
+              />
+            </div>
+          ) : (
+            <>
+              <div className="form-group">
+                <label>Email:</label>
+                <input
+                  type="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  className="form-input"
+                  disabled={isLoading}
+                />
+              </div>
+              <div className="form-group">
+                <label>Account type:</label>
+                <select value={role} onChange={(e) => setRole(e.target.value)} className="form-input">
+                  <option value="user">Bidder</option>
+                  <option value="seller">Seller</option>
+                </select>
+              </div>
+            </>
+          )}
+          <button type="submit" className="auth-button" disabled={isLoading}>
+            {token ? 'Set password' : 'Send reset link'}
+          </button>
+        </form>
+        <p className="auth-switch">
+          <Link to="/login">Back to login</Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default ResetPassword;
@@ -0,0 +1,94 @@
+import React, { useEffect, useState } from 'react';
+import { mfaEnroll, mfaVerify } from '../api';
+import './Auth.css';
+
+// Second login step for sellers and admins with two-factor authentication.
+// When the account still has to enroll, the TOTP secret is shown first and
+// the recovery codes returned by the first verification are displayed
+// before continuing.
+function SecondFactor({ challenge, onComplete }) {
+  const [enrollment, setEnrollment] = useState(null);
+  const [code, setCode] = useState('');
+  const [error, setError] = useState('');
+  const [result, setResult] = useState(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  useEffect(() => {
+    if (!challenge.enrollment_required) return;
+    mfaEnroll(challenge.mfa_token)
+      .then(setEnrollment)
+      .catch((err) => setError(err.message));
+  }, [challenge]);
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    setIsLoading(true);
+    try {
+      const data = await mfaVerify(challenge.mfa_token, code);
+      if (data.recovery_codes) {
+        setResult(data);
+      } else {
+        onComplete(data);
+      }
+    } catch (err) {
+      setError(err.message);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  if (result) {
+    return (
+      <div className="auth-container">
+        <div className="auth-box">
+          <h2>Save your recovery codes</h2>
+          <p className="welcome-message">
+            Each code works once if you lose access to your authenticator app. They will not be shown again.
+          </p>
+          <ul>
+            {result.recovery_codes.map((c) => <li key={c}><code>{c}</code></li>)}
+          </ul>
+          <button className="auth-button" onClick={() => onComplete(result)}>Continue</button>
+        </div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="auth-container">
+      <div className="auth-box">
+        <h2>Two-factor authentication</h2>
+        {error && <div className="error-message">{error}</div>}
+        {enrollment ? (
+          <p className="welcome-message">
+            Add this account to your authenticator app using the link or the secret below, then enter the 6-digit code it shows.
+            <br />
+            <a href={enrollment.otpauth_uri}>Open in authenticator</a>
+            <br />
+            <code>{enrollment.secret}</code>
+          </p>
+        ) : (
+          <p className="welcome-message">Enter the code from your authenticator app or a recovery code.</p>
+        )}
+        <form onSubmit={handleSubmit} className="auth-form">
+          <div className="form-group">
+            <label>Code:</label>
+            <input
+              type="text"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              required
+              autoComplete="one-time-code"
+              className="form-input"
+              disabled={isLoading}
+            />
+          </div>
+          <button type="submit" className="auth-button" disabled={isLoading}>Verify</button>
+        </form>
+      </div>
+    </div>
+  );
+}
+
+export default SecondFactor;
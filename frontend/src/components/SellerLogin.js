@@ -1,6 +1,7 @@
 import React, { useState } from 'react';
 import { Link, useNavigate } from 'react-router-dom';
-import { sellerLogin } from '../api';
+import { sellerLogin, errorMessage } from '../api';
+import SecondFactor from './SecondFactor';
 import './Auth.css';
 
 function SellerLogin() {
@@ -10,8 +11,20 @@ function SellerLogin() {
   });
   const [error, setError] = useState('');
   const [isLoading, setIsLoading] = useState(false);
+  const [challenge, setChallenge] = useState(null);
   const navigate = useNavigate();
 
+  const completeLogin = (data) => {
+    localStorage.setItem('sellerToken', data.token);
+    localStorage.setItem('sellerRefreshToken', data.refresh_token);
+    localStorage.setItem('sellerData', JSON.stringify({
+      id: data.account.id,
+      name: data.account.name,
+      email: data.account.email
+    }));
+    navigate('/seller-dashboard');
+  };
+
   const handleChange = (e) => {
     setFormData({ ...formData, [e.target.name]: e.target.value });
     setError(''); // Clear error when user types
@@ -26,17 +39,16 @@ function SellerLogin() {
       const data = await sellerLogin(formData);
       
       if (data.error) {
-        throw new Error(data.error);
+        throw new Error(errorMessage(data, 'Login failed'));
       }
       
-      if (data.token && data.seller) {
-        localStorage.setItem('sellerToken', data.token);
-        localStorage.setItem('sellerData', JSON.stringify({
-          id: data.seller.id,
-          name: data.seller.name,
-          email: data.seller.email
-        }));
-        navigate('/seller-dashboard');
+      if (data.mfa_required) {
+        setChallenge(data);
+      } else if (data.token && data.account) {
+        if (!data.account.roles.includes('seller')) {
+          throw new Error('This account cannot sell yet. Sign in as a bidder and start selling from your account.');
+        }
+        completeLogin(data);
       } else {
         throw new Error('Invalid response from server');
       }
@@ -48,6 +60,10 @@ function SellerLogin() {
     }
   };
 
+  if (challenge) {
+    return <SecondFactor challenge={challenge} onComplete={completeLogin} />;
+  }
+
   return (
     <div className="auth-container">
       <div className="auth-box">
@@ -1,6 +1,6 @@
 import React, { useState } from 'react';
 import { Link, useNavigate } from 'react-router-dom';
-import { sellerRegister } from '../api';
+import { sellerRegister, errorMessage } from '../api';
 import './SellerRegister.css';
 
 function SellerRegister() {
@@ -48,24 +48,25 @@ function SellerRegister() {
       });
       
       if (data.error) {
-        throw new Error(data.error);
+        throw new Error(errorMessage(data, 'Registration failed'));
       }
 
       // Check if we have all required data
-      if (!data.seller || !data.seller.id || !data.seller.name || !data.seller.email || !data.token) {
+      if (!data.account || !data.account.id || !data.account.name || !data.account.email || !data.token) {
         console.error('Invalid server response:', data);
         throw new Error('Invalid response from server');
       }
 
       // Store seller data in localStorage
       localStorage.setItem('sellerData', JSON.stringify({
-        id: data.seller.id,
-        name: data.seller.name,
-        email: data.seller.email
+        id: data.account.id,
+        name: data.account.name,
+        email: data.account.email
       }));
 
       // Store token
       localStorage.setItem('sellerToken', data.token);
+      localStorage.setItem('sellerRefreshToken', data.refresh_token);
       
       // Redirect to seller dashboard
       navigate('/seller-dashboard');
@@ -0,0 +1,32 @@
+import React, { useEffect, useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { verifyEmail } from '../api';
+import './Auth.css';
+
+// Landing page for the link in the verification email
+function VerifyEmail() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [status, setStatus] = useState(token ? 'Verifying...' : 'Missing verification token');
+
+  useEffect(() => {
+    if (!token) return;
+    verifyEmail(token)
+      .then((data) => setStatus(data.message))
+      .catch((err) => setStatus(err.message));
+  }, [token]);
+
+  return (
+    <div className="auth-container">
+      <div className="auth-box">
+        <h2>Email verification</h2>
+        <p className="welcome-message">{status}</p>
+        <p className="auth-switch">
+          <Link to="/login">Go to login</Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default VerifyEmail;